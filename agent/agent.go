@@ -1,49 +1,186 @@
 package agent
 
 import (
+	"fmt"
 	"log"
+	"math/rand"
 	"os"
 
 	"github.com/casen/snakegame/model"
-	"github.com/casen/snakegame/snake"
 	. "gorgonia.org/gorgonia"
 	"gorgonia.org/tensor"
 )
 
 type Agent struct {
-	dqn  *DQN
-	game *snake.Game
+	dqn *DQN
 }
 
-func NewAgent(game *snake.Game) *Agent {
+// Config holds the hyperparameters of an agent
+type Config struct {
+	Gamma        float32 // discount factor
+	Epsilon      float32 // exploration/exploitation bias, 1.0 is pure exploration
+	EpsilonMin   float32
+	EpsilonDecay float32
+	Neurons      int // width of the first hidden layer
 
-	// var times int = 1000
-	var gamma float32 = 0.95  // discount factor
-	var epsilon float32 = 1.0 // exploration/exploitation bias, set to 1.0/exploration by default
-	var epsilonDecayMin float32 = 0.01
-	var epsilonDecay float32 = 0.995
+	// A convolutional network instead of the dense one, for observations
+	// shaped channels x rows x cols
+	Conv bool
+
+	// How many memories each training step learns from, in a single pass
+	// through the network
+	BatchSize int
+
+	// The target network is synced with the online one every TargetSync
+	// training steps, or when Tau is above 0, blended towards it by Polyak
+	// averaging after every step
+	TargetSync int
+	Tau        float32
+	// Double DQN lets the online network pick the next action and the target
+	// network value it
+	DoubleDQN bool
+
+	// The most memories kept for replay, the oldest are evicted past this. At
+	// 0 it's sized to the observations, see memoryCapacity.
+	MemoryCapacity int
+
+	// Prioritized replay picks memories by how surprising they were rather
+	// than uniformly. PriorityAlpha is how much the priorities count, and the
+	// bias this brings in is corrected by a beta that grows from
+	// PriorityBetaStart to 1 over the first PriorityBetaSteps replayed memories.
+	Prioritized       bool
+	PriorityAlpha     float32
+	PriorityBetaStart float32
+	PriorityBetaSteps int
+
+	// Seeds the initial weights, the replay sampling and the exploration, so
+	// two runs with the same seed make exactly the same moves
+	Seed int64
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Gamma:        0.95,
+		Epsilon:      1.0,
+		EpsilonMin:   0.01,
+		EpsilonDecay: 0.995,
+		Neurons:      32,
+		BatchSize:    32,
+		TargetSync:   10,
+		DoubleDQN:    true,
+
+		PriorityAlpha:     0.6,
+		PriorityBetaStart: 0.4,
+		PriorityBetaSteps: 3200,
+	}
+}
+
+const (
+	maxMemories  = 100000
+	memoryBudget = 256 << 20 // bytes of observations kept for replay
+)
+
+// How many memories of observations of the given size fit in memoryBudget,
+// up to maxMemories. Each memory holds two observations of 4 byte floats, so
+// the board encoder on 20x20 would need about 1.5 GB for maxMemories.
+func memoryCapacity(inputs int) int {
+	return max(1, min(maxMemories, memoryBudget/(2*4*inputs)))
+}
+
+// Creates an agent that learns to play env. When env implements
+// model.Lookahead as well, the agent uses it to steer clear of deaths and
+// straight to food outside training.
+func NewAgent(env model.Env, cfg Config) (*Agent, error) {
+	sim, _ := env.(model.Lookahead)
+
+	if cfg.BatchSize < 1 {
+		return nil, fmt.Errorf("agent: batch size must be at least 1, got %d", cfg.BatchSize)
+	}
+
+	if cfg.Tau < 0 || cfg.Tau > 1 {
+		return nil, fmt.Errorf("agent: tau must be between 0 and 1, got %v", cfg.Tau)
+	}
+	if cfg.Tau == 0 && cfg.TargetSync < 1 {
+		return nil, fmt.Errorf("agent: target sync must be at least 1 step, got %d", cfg.TargetSync)
+	}
+
+	if cfg.MemoryCapacity < 0 {
+		return nil, fmt.Errorf("agent: memory capacity can't be negative, got %d", cfg.MemoryCapacity)
+	}
+
+	if cfg.Prioritized {
+		if cfg.PriorityAlpha < 0 || cfg.PriorityAlpha > 1 {
+			return nil, fmt.Errorf("agent: priority alpha must be between 0 and 1, got %v", cfg.PriorityAlpha)
+		}
+		if cfg.PriorityBetaStart < 0 || cfg.PriorityBetaStart > 1 {
+			return nil, fmt.Errorf("agent: priority beta must be between 0 and 1, got %v", cfg.PriorityBetaStart)
+		}
+		if cfg.PriorityBetaSteps < 0 {
+			return nil, fmt.Errorf("agent: priority beta steps can't be negative, got %d", cfg.PriorityBetaSteps)
+		}
+	}
+
+	shape := env.ObservationSpace().Shape
+	if cfg.Conv && len(shape) != 3 {
+		return nil, fmt.Errorf("agent: a conv network needs observations shaped channels x rows x cols, got %v", shape)
+	}
+
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	inputs := env.ObservationSpace().Size()
+	actions := env.ActionSpace().N
+	newBrain := func() *Brain {
+		if cfg.Conv {
+			return NewConvBrain(shape, actions, cfg.Neurons, cfg.BatchSize, rng)
+		}
+		return NewBrain(inputs, actions, cfg.Neurons, cfg.BatchSize, rng)
+	}
+
+	capacity := cfg.MemoryCapacity
+	if capacity == 0 {
+		capacity = memoryCapacity(inputs)
+	}
+	var memories Buffer = NewReplayBuffer(capacity, rng)
+	if cfg.Prioritized {
+		memories = NewPrioritizedBuffer(capacity, cfg.PriorityAlpha, cfg.PriorityBetaStart, cfg.PriorityBetaSteps, rng)
+	}
 
 	dqn := &DQN{
-		game:        game,
-		NN:          NewBrain(32),
-		gamma:       gamma,
-		epsilon:     epsilon,
-		epsDecayMin: epsilonDecayMin,
-		decay:       epsilonDecay,
+		env:         env,
+		sim:         sim,
+		NN:          newBrain(),
+		Target:      newBrain(),
+		conv:        cfg.Conv,
+		targetSync:  cfg.TargetSync,
+		tau:         cfg.Tau,
+		double:      cfg.DoubleDQN,
+		Memories:    memories,
+		gamma:       cfg.Gamma,
+		epsilon:     cfg.Epsilon,
+		epsDecayMin: cfg.EpsilonMin,
+		decay:       cfg.EpsilonDecay,
+		rng:         rng,
 	}
 	dqn.init()
 
 	return &Agent{
 		dqn: dqn,
-	}
+	}, nil
+}
+
+// Train plays the given number of episodes, 50 games each, learning from a
+// minibatch of memories after every episode
+func (a *Agent) Train(episodes int) error {
+	return a.dqn.Train(episodes)
 }
 
-func (a *Agent) Train() {
-	a.dqn.Train()
+// BestMove picks the action to take in state
+func (a *Agent) BestMove(state model.Observation) model.Action {
+	return a.dqn.BestMove(state)
 }
 
-func (a *Agent) BestMove() model.Vector {
-	return a.dqn.BestMove()
+// QValues are what the agent expects each action to be worth in state
+func (a *Agent) QValues(state model.Observation) ([]float32, error) {
+	return a.dqn.QValues(state)
 }
 
 func (a *Agent) Test() {
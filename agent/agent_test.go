@@ -0,0 +1,363 @@
+package agent
+
+import (
+	"math"
+	"testing"
+
+	"github.com/casen/snakegame/model"
+	"github.com/casen/snakegame/snake"
+)
+
+func newTestEnv(t *testing.T, seed int64) *snake.Env {
+	t.Helper()
+	cfg := snake.DefaultConfig()
+	cfg.Seed = seed
+	game, err := snake.NewGame(cfg)
+	if err != nil {
+		t.Fatalf("NewGame() = %v; want nil", err)
+	}
+	return snake.NewEnv(game)
+}
+
+func newTestAgent(t *testing.T, env model.Env, cfg Config) *Agent {
+	t.Helper()
+	a, err := NewAgent(env, cfg)
+	if err != nil {
+		t.Fatalf("NewAgent() = %v; want nil", err)
+	}
+	return a
+}
+
+// Plays a game with a fresh agent and returns the moves it made
+func playSeeded(t *testing.T, seed int64, steps int) []model.Action {
+	cfg := DefaultConfig()
+	cfg.Seed = seed
+	env := newTestEnv(t, seed)
+	ai := newTestAgent(t, env, cfg)
+
+	var moves []model.Action
+	state := env.Reset()
+	for i := 0; i < steps; i++ {
+		move := ai.BestMove(state)
+		moves = append(moves, move)
+
+		var done bool
+		if state, _, done, _ = env.Step(move); done {
+			break
+		}
+	}
+	return moves
+}
+
+func TestSameSeedSameTrajectory(t *testing.T) {
+	a := playSeeded(t, 7, 200)
+	b := playSeeded(t, 7, 200)
+
+	if len(a) != len(b) {
+		t.Fatalf("played %d and %d moves with the same seed; want the same", len(a), len(b))
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("move %d = %v and %v with the same seed; want the same", i, a[i], b[i])
+		}
+	}
+}
+
+func TestObservationSizeFlowsIntoBrain(t *testing.T) {
+	cfg := snake.DefaultConfig()
+	cfg.Rows, cfg.Cols = 6, 8
+	cfg.Encoder = snake.Grid{}
+	game, err := snake.NewGame(cfg)
+	if err != nil {
+		t.Fatalf("NewGame() = %v; want nil", err)
+	}
+	env := snake.NewEnv(game)
+	dqn := newTestAgent(t, env, DefaultConfig()).dqn
+
+	if got, want := dqn.NN.x.Shape()[1], 3*6*8; got != want {
+		t.Errorf("brain takes %d inputs; want %d", got, want)
+	}
+
+	state := env.Reset()
+	next, reward, done, _ := env.Step(0)
+	dqn.Memories.Add(Memory{State: state, Action: 0, Reward: reward, NextState: next, isDone: done})
+	if err := dqn.Replay(); err != nil {
+		t.Errorf("Replay() = %v; want nil", err)
+	}
+}
+
+func TestRelativeActions(t *testing.T) {
+	cfg := snake.DefaultConfig()
+	cfg.RelativeActions = true
+	game, err := snake.NewGame(cfg)
+	if err != nil {
+		t.Fatalf("NewGame() = %v; want nil", err)
+	}
+	env := snake.NewEnv(game)
+	ai := newTestAgent(t, env, DefaultConfig())
+
+	if got := ai.dqn.NN.y.Shape()[1]; got != 3 {
+		t.Errorf("brain has %d outputs; want 3", got)
+	}
+
+	state := env.Reset()
+	for i := 0; i < 50; i++ {
+		move := ai.BestMove(state)
+		if move < snake.TurnLeft || move > snake.TurnRight {
+			t.Fatalf("BestMove() = %v; want a turn", move)
+		}
+
+		var done bool
+		if state, _, done, _ = env.Step(move); done {
+			break
+		}
+	}
+}
+
+func TestSteersClearOfDeathWhateverTheRewards(t *testing.T) {
+	cfg := snake.DefaultConfig()
+	cfg.Rewards = snake.Sparse{Food: 1, Death: -1}
+
+	for seed := int64(1); seed <= 5; seed++ {
+		cfg.Seed = seed
+		game, err := snake.NewGame(cfg)
+		if err != nil {
+			t.Fatalf("NewGame() = %v; want nil", err)
+		}
+		env := snake.NewEnv(game)
+
+		agentCfg := DefaultConfig()
+		agentCfg.Seed = seed
+		agentCfg.Epsilon = 0
+		ai := newTestAgent(t, env, agentCfg)
+
+		// Heading north from the top row runs into the wall
+		if move := ai.BestMove(env.Reset()); move == 1 {
+			t.Errorf("seed %d: BestMove() = N; want anything but the wall", seed)
+		}
+	}
+}
+
+// An env that can't look ahead, like most games outside this repo
+type blindEnv struct{ model.Env }
+
+func TestAgentWithoutLookahead(t *testing.T) {
+	env := blindEnv{newTestEnv(t, 1)}
+	ai := newTestAgent(t, env, DefaultConfig())
+
+	if move := ai.BestMove(env.Reset()); move < 0 || int(move) >= env.ActionSpace().N {
+		t.Errorf("BestMove() = %v; want one of the %d actions", move, env.ActionSpace().N)
+	}
+}
+
+func TestNoExploringOutsideTraining(t *testing.T) {
+	env := blindEnv{newTestEnv(t, 1)}
+	dqn := newTestAgent(t, env, DefaultConfig()).dqn
+	dqn.epsilon = 1 // as a checkpoint saved early in training would have it
+
+	state := env.Reset()
+	values, err := dqn.QValues(state)
+	if err != nil {
+		t.Fatalf("QValues() = %v; want nil", err)
+	}
+	want := argmax(values, env.LegalActions())
+	for i := 0; i < 20; i++ {
+		if got := dqn.BestMove(state); got != want {
+			t.Fatalf("BestMove() = %v; want the best valued %v", got, want)
+		}
+	}
+}
+
+func TestQValuePerAction(t *testing.T) {
+	env := newTestEnv(t, 1)
+	dqn := newTestAgent(t, env, DefaultConfig()).dqn
+
+	values, err := dqn.QValues(env.Reset())
+	if err != nil {
+		t.Fatalf("QValues() = %v; want nil", err)
+	}
+	if len(values) != env.ActionSpace().N {
+		t.Errorf("len(QValues()) = %d; want %d", len(values), env.ActionSpace().N)
+	}
+}
+
+func TestLossMaskedToActionTaken(t *testing.T) {
+	env := newTestEnv(t, 1)
+	dqn := newTestAgent(t, env, DefaultConfig()).dqn
+
+	state := env.Reset()
+	const taken = 2
+	next, reward, _, _ := env.Step(taken)
+	dqn.Memories.Add(Memory{State: state, Action: taken, Reward: reward, NextState: next, isDone: true})
+
+	// The output layer has a column per action, only the one taken may learn
+	out := dqn.NN.learnables()[len(dqn.NN.learnables())-1]
+	before := append([]float32(nil), out.Value().Data().([]float32)...)
+
+	if err := dqn.Replay(); err != nil {
+		t.Fatalf("Replay() = %v; want nil", err)
+	}
+
+	actions := out.Shape()[1]
+	after := out.Value().Data().([]float32)
+	var changed bool
+	for i := range after {
+		if after[i] == before[i] {
+			continue
+		}
+		if i%actions != taken {
+			t.Errorf("weight %d of action %d changed; want only action %d to learn", i, i%actions, taken)
+		}
+		changed = true
+	}
+	if !changed {
+		t.Errorf("Replay() left the output layer alone; want action %d to learn", taken)
+	}
+}
+
+// Reads the first weight of the first layer of a brain
+func firstWeight(nn *Brain) float32 {
+	return nn.learnables()[0].Value().Data().([]float32)[0]
+}
+
+func TestTargetSync(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.TargetSync = 3
+	dqn := newTestAgent(t, newTestEnv(t, 1), cfg).dqn
+
+	if firstWeight(dqn.Target) != firstWeight(dqn.NN) {
+		t.Fatalf("target starts at %v; want a copy of %v", firstWeight(dqn.Target), firstWeight(dqn.NN))
+	}
+
+	start := firstWeight(dqn.Target)
+	dqn.NN.learnables()[0].Value().Data().([]float32)[0] = start + 1
+
+	for step := 1; step <= 3; step++ {
+		dqn.updateTarget()
+		want := start
+		if step == 3 {
+			want = start + 1
+		}
+		if got := firstWeight(dqn.Target); got != want {
+			t.Errorf("after step %d target = %v; want %v", step, got, want)
+		}
+	}
+}
+
+func TestTargetPolyak(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Tau = 0.5
+	dqn := newTestAgent(t, newTestEnv(t, 1), cfg).dqn
+
+	start := firstWeight(dqn.Target)
+	dqn.NN.learnables()[0].Value().Data().([]float32)[0] = start + 1
+
+	dqn.updateTarget()
+	if got, want := firstWeight(dqn.Target), start+0.5; math.Abs(float64(got-want)) > 1e-6 {
+		t.Errorf("after one step target = %v; want %v", got, want)
+	}
+}
+
+func TestActorThinksWithNN(t *testing.T) {
+	env := newTestEnv(t, 1)
+	cfg := DefaultConfig()
+	cfg.BatchSize = 4
+	dqn := newTestAgent(t, env, cfg).dqn
+
+	state := env.Reset()
+	for i := 0; i < 4; i++ {
+		next, reward, done, _ := env.Step(model.Action(i % 2))
+		dqn.Memories.Add(Memory{State: state, Action: model.Action(i % 2), Reward: reward, NextState: next, isDone: done})
+		state = next
+	}
+	if err := dqn.Replay(); err != nil {
+		t.Fatalf("Replay() = %v; want nil", err)
+	}
+
+	// Picking moves one state at a time has to see what the batch learnt
+	got, err := dqn.QValues(state)
+	if err != nil {
+		t.Fatalf("QValues() = %v; want nil", err)
+	}
+	batch, err := predict(dqn.NN, dqn.VM, []model.Observation{state})
+	if err != nil {
+		t.Fatalf("predict() = %v; want nil", err)
+	}
+	for a := range got {
+		if math.Abs(float64(got[a]-batch[0][a])) > 1e-6 {
+			t.Errorf("QValues()[%d] = %v; want %v as in the batch", a, got[a], batch[0][a])
+		}
+	}
+}
+
+func TestDoubleDQNPicksWithNNValuesWithTarget(t *testing.T) {
+	env := newTestEnv(t, 1)
+	cfg := DefaultConfig()
+	cfg.DoubleDQN = true
+	cfg.BatchSize = 4
+	dqn := newTestAgent(t, env, cfg).dqn
+
+	// A network of another seed, so NN and Target disagree
+	cfg.Seed = 2
+	dqn.NN.blend(newTestAgent(t, env, cfg).dqn.NN, 1)
+
+	var states []model.Observation
+	var moves [][]model.Action
+	state := env.Reset()
+	for i := 0; i < 4; i++ {
+		states, moves = append(states, state), append(moves, env.LegalActions())
+		state, _, _, _ = env.Step(model.Action(i % 2))
+	}
+
+	got, err := dqn.bootstrap(states, moves)
+	if err != nil {
+		t.Fatalf("bootstrap() = %v; want nil", err)
+	}
+	target, err := predict(dqn.Target, dqn.targetVM, states)
+	if err != nil {
+		t.Fatalf("predict() = %v; want nil", err)
+	}
+
+	disagree := false
+	for i, s := range states {
+		values, err := dqn.QValues(s)
+		if err != nil {
+			t.Fatalf("QValues() = %v; want nil", err)
+		}
+		picked := argmax(values, moves[i])
+		if picked != argmax(target[i], moves[i]) {
+			disagree = true
+		}
+		if want := target[i][picked]; math.Abs(float64(got[i]-want)) > 1e-6 {
+			t.Errorf("bootstrap()[%d] = %v; want Target's value %v of NN's pick %v", i, got[i], want, picked)
+		}
+	}
+	if !disagree {
+		t.Fatalf("NN and Target pick the same actions; the test needs networks that disagree")
+	}
+}
+
+func TestMemorySizedToObservations(t *testing.T) {
+	if got := newTestAgent(t, newTestEnv(t, 1), DefaultConfig()).dqn.Memories.Cap(); got != maxMemories {
+		t.Errorf("memory capacity for the features = %d; want %d", got, maxMemories)
+	}
+
+	cfg := snake.DefaultConfig()
+	cfg.Rows, cfg.Cols = 20, 20
+	cfg.Encoder = snake.BoardTensor{}
+	game, err := snake.NewGame(cfg)
+	if err != nil {
+		t.Fatalf("NewGame() = %v; want nil", err)
+	}
+	env := snake.NewEnv(game)
+	memories := newTestAgent(t, env, DefaultConfig()).dqn.Memories
+	if bytes := memories.Cap() * 2 * 4 * env.ObservationSpace().Size(); bytes > memoryBudget {
+		t.Errorf("memories of the board on 20x20 take up to %d bytes; want at most %d", bytes, memoryBudget)
+	}
+
+	agentCfg := DefaultConfig()
+	agentCfg.MemoryCapacity = 10
+	if got := newTestAgent(t, env, agentCfg).dqn.Memories.Cap(); got != 10 {
+		t.Errorf("memory capacity = %d; want the 10 asked for", got)
+	}
+}
@@ -1,17 +1,29 @@
 package agent
 
 import (
+	"math"
+	"math/rand"
+
+	"github.com/casen/snakegame/model"
 	. "gorgonia.org/gorgonia"
 	"gorgonia.org/tensor"
 )
 
 type Layer struct {
 	W   *Node
+	Op  func(x, w *Node) (*Node, error) // how the weights are applied, a matrix multiplication when nil
 	Act func(x *Node) (*Node, error)
 }
 
 func (l *Layer) fwd(x *Node) (*Node, error) {
-	xw := Must(Mul(x, l.W))
+	op := l.Op
+	if op == nil {
+		op = Mul
+	}
+	xw, err := op(x, l.W)
+	if err != nil {
+		return nil, err
+	}
 	if l.Act == nil {
 		return xw, nil
 	}
@@ -22,54 +34,81 @@ func (l *Layer) fwd(x *Node) (*Node, error) {
 
 type Brain struct {
 	g *ExprGraph
-	x *Node
-	y *Node
+	x *Node // a batch of observations, one per row
+	y *Node // the target Q-value of each action
+	w *Node // how much each action counts in the loss: only the one taken, scaled by importance sampling
 	l []Layer
 
 	pred    *Node
 	predVal Value
 }
 
-func NewBrain(numNeurons int) *Brain {
+// Creates the network mapping batches of observations of the given size to a
+// Q-value for each of the actions, with weights drawn from rng
+func NewBrain(inputs, actions int, numNeurons int, batch int, rng *rand.Rand) *Brain {
 	g := NewGraph()
 
-	x := NewMatrix(g, of, WithShape(1, 11), WithName("X"), WithInit(Zeroes()))
-	y := NewMatrix(g, of, WithShape(1, 4), WithName("Y"), WithInit(Zeroes()))
 	l := []Layer{
-		{W: NewMatrix(g, tensor.Float32, WithShape(11, numNeurons), WithName("L0W"), WithInit(GlorotU(1.0))), Act: Rectify},
-		{W: NewMatrix(g, tensor.Float32, WithShape(numNeurons, 20), WithName("L1W"), WithInit(GlorotU(1.0))), Act: Rectify},
-		{W: NewMatrix(g, tensor.Float32, WithShape(20, 50), WithName("L2W"), WithInit(GlorotU(1.0))), Act: Rectify},
-		{W: NewMatrix(g, tensor.Float32, WithShape(50, 4), WithName("L3W"), WithInit(GlorotU(1.0)))},
+		{W: NewMatrix(g, tensor.Float32, WithShape(inputs, numNeurons), WithName("L0W"), WithInit(glorotU(rng))), Act: Rectify},
+		{W: NewMatrix(g, tensor.Float32, WithShape(numNeurons, 20), WithName("L1W"), WithInit(glorotU(rng))), Act: Rectify},
+		{W: NewMatrix(g, tensor.Float32, WithShape(20, 50), WithName("L2W"), WithInit(glorotU(rng))), Act: Rectify},
+		{W: NewMatrix(g, tensor.Float32, WithShape(50, actions), WithName("L3W"), WithInit(glorotU(rng)))},
 	}
+	return newBrain(g, batch, inputs, actions, l)
+}
+
+func newBrain(g *ExprGraph, batch, inputs, actions int, l []Layer) *Brain {
+	x := NewMatrix(g, of, WithShape(batch, inputs), WithName("X"), WithInit(Zeroes()))
+	y := NewMatrix(g, of, WithShape(batch, actions), WithName("Y"), WithInit(Zeroes()))
+	w := NewMatrix(g, of, WithShape(batch, actions), WithName("ISW"), WithInit(Zeroes()))
 	return &Brain{
 		g: g,
 		x: x,
 		y: y,
+		w: w,
 		l: l,
 	}
 }
 
-func (nn *Brain) forward(x *Node) (*Node, error) {
-	var err error
-	pred := x
-	pred, err = nn.l[0].fwd(pred)
-	if err != nil {
-		return nil, err
-	}
-	pred, err = nn.l[1].fwd(pred)
-	if err != nil {
-		return nil, err
-	}
-	pred, err = nn.l[2].fwd(pred)
-	if err != nil {
-		return nil, err
-	}
-	pred, err = SoftMax(pred)
-	if err != nil {
-		return nil, err
+// Builds a brain for batches of another size on a graph of its own, thinking
+// with the very same weights as nn. Whatever nn learns, it knows too.
+func (nn *Brain) withBatch(batch int) *Brain {
+	g := NewGraph()
+
+	l := make([]Layer, len(nn.l))
+	for i, layer := range nn.l {
+		l[i] = Layer{
+			W:   NewTensor(g, of, layer.W.Dims(), WithShape(layer.W.Shape()...), WithName(layer.W.Name()), WithValue(layer.W.Value())),
+			Op:  layer.Op,
+			Act: layer.Act,
+		}
 	}
+	return newBrain(g, batch, nn.x.Shape()[1], nn.y.Shape()[1], l)
+}
 
-	return pred, nil
+// The most observations the brain takes at once
+func (nn *Brain) batchSize() int {
+	return nn.x.Shape()[0]
+}
+
+// Glorot uniform initialization like gorgonia's GlorotU, but drawing from our
+// own rng, since gorgonia's can't be seeded
+func glorotU(rng *rand.Rand) InitWFn {
+	return func(dt tensor.Dtype, s ...int) interface{} {
+		fanIn, fanOut := s[0], s[len(s)-1]
+		if len(s) == 4 {
+			// Conv filters are out channels x in channels x kernel
+			kernel := s[2] * s[3]
+			fanIn, fanOut = s[1]*kernel, s[0]*kernel
+		}
+		limit := math.Sqrt(6.0 / float64(fanIn+fanOut))
+
+		retVal := make([]float32, tensor.Shape(s).TotalSize())
+		for i := range retVal {
+			retVal[i] = float32((rng.Float64()*2 - 1) * limit)
+		}
+		return retVal
+	}
 }
 
 func (nn *Brain) learnables() Nodes {
@@ -82,7 +121,8 @@ func (nn *Brain) learnables() Nodes {
 
 func (nn *Brain) model() []ValueGrad { return NodesToValueGrads(nn.learnables()) }
 
-func (nn *Brain) cons() (pred *Node, err error) {
+// Builds the graph for inference only, which is all a target network needs
+func (nn *Brain) consPred() (pred *Node, err error) {
 	pred = nn.x
 	for _, l := range nn.l {
 		if pred, err = l.fwd(pred); err != nil {
@@ -92,7 +132,18 @@ func (nn *Brain) cons() (pred *Node, err error) {
 	nn.pred = pred
 	Read(nn.pred, &nn.predVal)
 
-	cost := Must(Mean(Must(Square(Must(Sub(nn.y, pred))))))
+	return pred, nil
+}
+
+func (nn *Brain) cons() (pred *Node, err error) {
+	if pred, err = nn.consPred(); err != nil {
+		return nil, err
+	}
+
+	// Squared error of the action taken, scaled by the importance sampling
+	// weight of the memory and averaged over the batch. The other actions
+	// weigh 0, we learnt nothing about them.
+	cost := Must(Sum(Must(HadamardProd(nn.w, Must(Square(Must(Sub(nn.y, pred))))))))
 	if _, err = Grad(cost, nn.learnables()...); err != nil {
 		return nil, err
 	}
@@ -100,28 +151,53 @@ func (nn *Brain) cons() (pred *Node, err error) {
 	return pred, nil
 }
 
-func (nn *Brain) Let2(xs [11]float32, y float32) {
-	xval := nn.x.Value().Data().([]float32)
-	yval := nn.y.Value().Data().([]float32)
-
-	//  overwrite the data
-	for i := range xval {
-		xval[i] = xs[i]
+// Copies the weights of src into the brain, blending them in at rate tau.
+// A tau of 1 makes an exact copy.
+func (nn *Brain) blend(src *Brain, tau float32) {
+	srcLearnables := src.learnables()
+	for i, w := range nn.learnables() {
+		dst := w.Value().Data().([]float32)
+		from := srcLearnables[i].Value().Data().([]float32)
+		if tau == 1 {
+			copy(dst, from)
+			continue
+		}
+		for j := range dst {
+			dst[j] = tau*from[j] + (1-tau)*dst[j]
+		}
 	}
+}
+
+// Sets a batch of input states and the target Q-value of the action taken in
+// each, the loss scaled by their weights. Rows past the end of the batch are
+// left out of the loss.
+func (nn *Brain) Let2(xs []model.Observation, actions []model.Action, ys []float32, weights []float32) {
+	nn.Let1(xs)
+
+	yval := nn.y.Value().Data().([]float32)
+	wval := nn.w.Value().Data().([]float32)
 	for i := range yval {
 		yval[i] = 0
+		wval[i] = 0
 	}
 
-	// For now, assume all the magic ends up in the first value of this array
-	// TODO gain more clarity on out input state vector x related to output vector y
-	yval[0] = y
+	numActions := nn.y.Shape()[1]
+	for i, a := range actions {
+		yval[i*numActions+int(a)] = ys[i]
+		wval[i*numActions+int(a)] = weights[i] / float32(len(xs))
+	}
 }
 
-func (nn *Brain) Let1(x [11]float32) {
+// Sets a batch of input states, zeroing the rows past the end of it
+func (nn *Brain) Let1(xs []model.Observation) {
 	xval := nn.x.Value().Data().([]float32)
+	inputs := nn.x.Shape()[1]
+
 	// overwrite the data
 	for i := range xval {
-		xval[i] = x[i]
+		xval[i] = 0
+	}
+	for i, x := range xs {
+		copy(xval[i*inputs:(i+1)*inputs], x)
 	}
-
 }
@@ -0,0 +1,190 @@
+package agent
+
+import (
+	"encoding/gob"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/casen/snakegame/model"
+)
+
+// Bump this whenever the layout of the network or the checkpoint changes, so
+// that stale weights are refused instead of silently loaded
+const checkpointVersion = 4
+
+type checkpoint struct {
+	Version   int
+	StateSize int
+	Conv      bool
+
+	// The variant of the env the agent was trained on, see model.Variant
+	Env map[string]string
+
+	Gamma       float32
+	Epsilon     float32
+	EpsDecayMin float32
+	Decay       float32
+
+	Layers []layerWeights
+}
+
+type layerWeights struct {
+	Name  string
+	Shape []int
+	Data  []float32
+}
+
+// Save writes the learned weights of the agent's brain, along with the
+// hyperparameters needed to pick up where training left off
+func (a *Agent) Save(path string) error {
+	dqn := a.dqn
+	ckpt := checkpoint{
+		Version:     checkpointVersion,
+		StateSize:   dqn.NN.x.Shape()[1],
+		Conv:        dqn.conv,
+		Env:         variant(dqn.env),
+		Gamma:       dqn.gamma,
+		Epsilon:     dqn.epsilon,
+		EpsDecayMin: dqn.epsDecayMin,
+		Decay:       dqn.decay,
+	}
+
+	for _, w := range dqn.NN.learnables() {
+		data := w.Value().Data().([]float32)
+		ckpt.Layers = append(ckpt.Layers, layerWeights{
+			Name:  w.Name(),
+			Shape: append([]int(nil), w.Shape()...),
+			Data:  append([]float32(nil), data...),
+		})
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := gob.NewEncoder(f).Encode(ckpt); err != nil {
+		f.Close()
+		return fmt.Errorf("writing checkpoint %s: %w", path, err)
+	}
+
+	return f.Close()
+}
+
+// Load creates an agent for env and restores the weights saved in the
+// checkpoint at path. The kind and width of the network and the
+// hyperparameters saved in the checkpoint take the place of those in cfg. Checkpoints trained on
+// another variant of the env, or whose layers don't line up with the brain
+// we'd build for env, are refused.
+func Load(path string, env model.Env, cfg Config) (*Agent, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var ckpt checkpoint
+	if err := gob.NewDecoder(f).Decode(&ckpt); err != nil {
+		return nil, fmt.Errorf("reading checkpoint %s: %w", path, err)
+	}
+
+	if ckpt.Version != checkpointVersion {
+		return nil, fmt.Errorf("checkpoint %s has version %d, want %d", path, ckpt.Version, checkpointVersion)
+	}
+
+	if err := sameVariant(ckpt.Env, variant(env)); err != nil {
+		return nil, fmt.Errorf("checkpoint %s %w", path, err)
+	}
+
+	cfg.Conv = ckpt.Conv
+	if neurons := ckpt.neurons(); neurons > 0 {
+		cfg.Neurons = neurons
+	}
+	a, err := NewAgent(env, cfg)
+	if err != nil {
+		return nil, err
+	}
+	dqn := a.dqn
+
+	if stateSize := dqn.NN.x.Shape()[1]; ckpt.StateSize != stateSize {
+		return nil, fmt.Errorf("checkpoint %s has state size %d, want %d", path, ckpt.StateSize, stateSize)
+	}
+
+	learnables := dqn.NN.learnables()
+	if len(ckpt.Layers) != len(learnables) {
+		return nil, fmt.Errorf("checkpoint %s has %d layers, want %d", path, len(ckpt.Layers), len(learnables))
+	}
+
+	for i, w := range learnables {
+		layer := ckpt.Layers[i]
+		if !sameShape(layer.Shape, w.Shape()) || len(layer.Data) != w.Shape().TotalSize() {
+			return nil, fmt.Errorf("checkpoint %s: layer %s has shape %v, want %v", path, layer.Name, layer.Shape, w.Shape())
+		}
+	}
+
+	for i, w := range learnables {
+		copy(w.Value().Data().([]float32), ckpt.Layers[i].Data)
+	}
+	dqn.Target.blend(dqn.NN, 1)
+
+	dqn.gamma = ckpt.Gamma
+	dqn.epsilon = ckpt.Epsilon
+	dqn.epsDecayMin = ckpt.EpsDecayMin
+	dqn.decay = ckpt.Decay
+
+	return a, nil
+}
+
+// The width of the first hidden layer of the saved network, 0 when the
+// checkpoint doesn't have one
+func (c checkpoint) neurons() int {
+	for _, l := range c.Layers {
+		if l.Name == "L0W" && len(l.Shape) == 2 {
+			return l.Shape[1]
+		}
+	}
+	return 0
+}
+
+// The variant of env, nil when it doesn't come in any
+func variant(env model.Env) map[string]string {
+	if v, ok := env.(model.Variant); ok {
+		return v.Variant()
+	}
+	return nil
+}
+
+// Tells what the checkpoint was trained on that the env doesn't match, if
+// anything
+func sameVariant(trained, env map[string]string) error {
+	var keys []string
+	for k := range trained {
+		keys = append(keys, k)
+	}
+	for k := range env {
+		if _, ok := trained[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		if trained[k] != env[k] {
+			return fmt.Errorf("was trained with %s %q, the game has %q", k, trained[k], env[k])
+		}
+	}
+	return nil
+}
+
+func sameShape(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
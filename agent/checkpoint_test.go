@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/casen/snakegame/model"
+	"github.com/casen/snakegame/snake"
+)
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snake.ckpt")
+
+	saved := newTestAgent(t, newTestEnv(t, 1), DefaultConfig())
+	saved.dqn.epsilon = 0.25
+	if err := saved.Save(path); err != nil {
+		t.Fatalf("Save() = %v; want nil", err)
+	}
+
+	loaded, err := Load(path, newTestEnv(t, 1), DefaultConfig())
+	if err != nil {
+		t.Fatalf("Load() = %v; want nil", err)
+	}
+
+	if loaded.dqn.epsilon != 0.25 {
+		t.Errorf("Load() epsilon = %v; want %v", loaded.dqn.epsilon, 0.25)
+	}
+
+	want := saved.dqn.NN.learnables()
+	for i, w := range loaded.dqn.NN.learnables() {
+		gotData := w.Value().Data().([]float32)
+		wantData := want[i].Value().Data().([]float32)
+		for j := range wantData {
+			if gotData[j] != wantData[j] {
+				t.Fatalf("Load() layer %s[%d] = %v; want %v", w.Name(), j, gotData[j], wantData[j])
+			}
+		}
+	}
+}
+
+func TestLoadRebuildsSavedWidth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snake.ckpt")
+
+	cfg := DefaultConfig()
+	cfg.Neurons = 16
+	saved := newTestAgent(t, newTestEnv(t, 1), cfg)
+	if err := saved.Save(path); err != nil {
+		t.Fatalf("Save() = %v; want nil", err)
+	}
+
+	// The config loading it asks for the default width
+	loaded, err := Load(path, newTestEnv(t, 1), DefaultConfig())
+	if err != nil {
+		t.Fatalf("Load() = %v; want nil", err)
+	}
+	if got := loaded.dqn.NN.learnables()[0].Shape()[1]; got != 16 {
+		t.Errorf("Load() first hidden layer has %d neurons; want 16", got)
+	}
+}
+
+func TestLoadRejectsMismatchedShapes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snake.ckpt")
+
+	// A network for a game of three actions, where this one has four
+	a := newTestAgent(t, newTestEnv(t, 1), DefaultConfig())
+	a.dqn.NN = NewBrain(11, 3, 32, 1, rand.New(rand.NewSource(1)))
+	if err := a.Save(path); err != nil {
+		t.Fatalf("Save() = %v; want nil", err)
+	}
+
+	if _, err := Load(path, newTestEnv(t, 1), DefaultConfig()); err == nil {
+		t.Errorf("Load() = nil; want shape mismatch error")
+	}
+}
+
+// An env that sees the game the same way as the one it wraps, but claims to be
+// another variant of it
+type variantEnv struct {
+	model.Env
+	variant map[string]string
+}
+
+func (e variantEnv) Variant() map[string]string { return e.variant }
+
+func TestLoadRejectsOtherVariants(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snake.ckpt")
+
+	if err := newTestAgent(t, newTestEnv(t, 1), DefaultConfig()).Save(path); err != nil {
+		t.Fatalf("Save() = %v; want nil", err)
+	}
+
+	other := variantEnv{newTestEnv(t, 1), map[string]string{"encoder": "Other"}}
+	if _, err := Load(path, other, DefaultConfig()); err == nil || !strings.Contains(err.Error(), "encoder") {
+		t.Errorf("Load() = %v; want an error about the encoder", err)
+	}
+
+	if _, err := Load(path, newTestEnv(t, 1), DefaultConfig()); err != nil {
+		t.Errorf("Load() on the same variant = %v; want nil", err)
+	}
+}
+
+func TestLoadRejectsOtherWalls(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snake.ckpt")
+
+	// The same encoder sees as many values whether or not the board wraps
+	// around, so only the variant tells the two games apart
+	newEnv := func(walls snake.WallMode) *snake.Env {
+		cfg := snake.DefaultConfig()
+		cfg.Walls = walls
+		game, err := snake.NewGame(cfg)
+		if err != nil {
+			t.Fatalf("NewGame() = %v; want nil", err)
+		}
+		return snake.NewEnv(game)
+	}
+	if err := newTestAgent(t, newEnv(snake.SolidWalls), DefaultConfig()).Save(path); err != nil {
+		t.Fatalf("Save() = %v; want nil", err)
+	}
+
+	if _, err := Load(path, newEnv(snake.WrapAround), DefaultConfig()); err == nil || !strings.Contains(err.Error(), "walls") {
+		t.Errorf("Load() on a wrapping board = %v; want an error about the walls", err)
+	}
+	if _, err := Load(path, newEnv(snake.SolidWalls), DefaultConfig()); err != nil {
+		t.Errorf("Load() on solid walls = %v; want nil", err)
+	}
+}
@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"math/rand"
+
+	. "gorgonia.org/gorgonia"
+	"gorgonia.org/tensor"
+)
+
+// Creates a convolutional network for observations shaped channels x rows x
+// cols, like a board with a channel for each kind of thing on it. Two 3x3
+// convolutions look at every cell along with its neighbours, keeping the size
+// of the board, before dense layers turn what they found into a Q-value for
+// each of the actions. It runs on the CPU.
+func NewConvBrain(shape []int, actions int, numNeurons int, batch int, rng *rand.Rand) *Brain {
+	g := NewGraph()
+
+	channels, rows, cols := shape[0], shape[1], shape[2]
+	l := []Layer{
+		{W: NewTensor(g, of, 4, WithShape(16, channels, 3, 3), WithName("C0W"), WithInit(glorotU(rng))), Op: conv3x3(shape), Act: Rectify},
+		{W: NewTensor(g, of, 4, WithShape(32, 16, 3, 3), WithName("C1W"), WithInit(glorotU(rng))), Op: conv3x3(shape), Act: Rectify},
+		{W: NewMatrix(g, of, WithShape(32*rows*cols, numNeurons), WithName("L0W"), WithInit(glorotU(rng))), Op: flatMul, Act: Rectify},
+		{W: NewMatrix(g, of, WithShape(numNeurons, actions), WithName("L1W"), WithInit(glorotU(rng)))},
+	}
+	return newBrain(g, batch, channels*rows*cols, actions, l)
+}
+
+// A 3x3 convolution, padded to keep the size of the board. Flat observations
+// are first unfolded into the given shape.
+func conv3x3(shape []int) func(x, w *Node) (*Node, error) {
+	return func(x, w *Node) (*Node, error) {
+		if x.Dims() == 2 {
+			var err error
+			if x, err = Reshape(x, append(tensor.Shape{x.Shape()[0]}, shape...)); err != nil {
+				return nil, err
+			}
+		}
+		return Conv2d(x, w, tensor.Shape{3, 3}, []int{1, 1}, []int{1, 1}, []int{1, 1})
+	}
+}
+
+// Flattens each of the batch into a row before multiplying by the weights
+func flatMul(x, w *Node) (*Node, error) {
+	batch := x.Shape()[0]
+	flat, err := Reshape(x, tensor.Shape{batch, x.Shape().TotalSize() / batch})
+	if err != nil {
+		return nil, err
+	}
+	return Mul(flat, w)
+}
@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/casen/snakegame/model"
+	"github.com/casen/snakegame/snake"
+)
+
+func newBoardTensorEnv(t *testing.T) *snake.Env {
+	t.Helper()
+	cfg := snake.DefaultConfig()
+	cfg.Rows, cfg.Cols = 6, 6
+	cfg.Seed = 1
+	cfg.Encoder = snake.BoardTensor{}
+	game, err := snake.NewGame(cfg)
+	if err != nil {
+		t.Fatalf("NewGame() = %v; want nil", err)
+	}
+	return snake.NewEnv(game)
+}
+
+func TestConvBrainLearns(t *testing.T) {
+	env := newBoardTensorEnv(t)
+	cfg := DefaultConfig()
+	cfg.Conv = true
+	cfg.BatchSize = 4
+	dqn := newTestAgent(t, env, cfg).dqn
+
+	state := env.Reset()
+	for i := 0; i < 4; i++ {
+		next, reward, done, _ := env.Step(model.Action(i % 2))
+		dqn.Memories.Add(Memory{State: state, Action: model.Action(i % 2), Reward: reward, NextState: next, isDone: done})
+		state = next
+	}
+
+	before, err := dqn.QValues(state)
+	if err != nil {
+		t.Fatalf("QValues() = %v; want nil", err)
+	}
+	if len(before) != env.ActionSpace().N {
+		t.Fatalf("len(QValues()) = %d; want %d", len(before), env.ActionSpace().N)
+	}
+
+	if err := dqn.Replay(); err != nil {
+		t.Fatalf("Replay() = %v; want nil", err)
+	}
+
+	after, err := dqn.QValues(state)
+	if err != nil {
+		t.Fatalf("QValues() = %v; want nil", err)
+	}
+	var changed bool
+	for a := range after {
+		changed = changed || after[a] != before[a]
+	}
+	if !changed {
+		t.Errorf("QValues() = %v after Replay(); want them to move", after)
+	}
+}
+
+func TestConvNeedsBoardShapedObservations(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Conv = true
+	if _, err := NewAgent(newTestEnv(t, 1), cfg); err == nil {
+		t.Errorf("NewAgent() = nil; want an error for flat observations")
+	}
+}
+
+func TestSaveLoadConv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snake.ckpt")
+
+	cfg := DefaultConfig()
+	cfg.Conv = true
+	saved := newTestAgent(t, newBoardTensorEnv(t), cfg)
+	if err := saved.Save(path); err != nil {
+		t.Fatalf("Save() = %v; want nil", err)
+	}
+
+	// The checkpoint knows it's a conv network, the config doesn't have to
+	loaded, err := Load(path, newBoardTensorEnv(t), DefaultConfig())
+	if err != nil {
+		t.Fatalf("Load() = %v; want nil", err)
+	}
+	if !loaded.dqn.conv {
+		t.Errorf("Load() gave a dense network; want the conv one saved")
+	}
+}
@@ -3,32 +3,45 @@ package agent
 import (
 	"log"
 	"math/rand"
-	"time"
 
 	. "github.com/casen/snakegame/model"
-	"github.com/casen/snakegame/snake"
 	"gorgonia.org/gorgonia"
 )
 
-var cardinals = [4]Vector{
-	{X: 0, Y: 1},  // E
-	{X: -1, Y: 0}, // N
-	{X: 1, Y: 0},  // S
-	{X: 0, Y: -1}, // W
-}
-
 type DQN struct {
-	game *snake.Game
-	NN   *Brain
+	env  Env
+	sim  Lookahead // the same env if it can look ahead, used to steer clear of deaths outside training
+	NN   *Brain    // trained a whole minibatch at a time
+	conv bool      // NN is a conv network
 	gorgonia.VM
 	gorgonia.Solver
-	Memories []Memory // The Q-Table - stores State/Action/Reward/NextState/NextMoves/IsDone - added to each train x times per episode
+
+	// NN again, on a graph for a single observation, to pick moves with
+	actor   *Brain
+	actorVM gorgonia.VM
+
+	// NN again, on a graph for a whole minibatch without the gradients, to
+	// pick the next actions with in double DQN
+	picker   *Brain
+	pickerVM gorgonia.VM
+
+	// The target network lags behind NN and supplies the bootstrapped values
+	// in Replay, so NN isn't chasing its own predictions
+	Target     *Brain
+	targetVM   gorgonia.VM
+	targetSync int     // copy NN into Target every this many training steps
+	tau        float32 // when above 0, blend NN into Target at this rate after every step instead
+	double     bool    // NN picks the next action and Target values it
+	steps      int     // training steps taken so far
+	syncs      int
+	Memories   Buffer // The Q-Table - stores State/Action/Reward/NextState/NextMoves/IsDone - added to each train x times per episode
 
 	gamma       float32
 	epsilon     float32
 	epsDecayMin float32
 	decay       float32
 	isTraining  bool
+	rng         *rand.Rand
 }
 
 func (agent *DQN) init() {
@@ -41,103 +54,123 @@ func (agent *DQN) init() {
 	agent.VM = gorgonia.NewTapeMachine(agent.NN.g)
 	agent.Solver = gorgonia.NewRMSPropSolver()
 	agent.isTraining = false
+
+	agent.actor = agent.NN.withBatch(1)
+	if _, err := agent.actor.consPred(); err != nil {
+		panic(err)
+	}
+	agent.actorVM = gorgonia.NewTapeMachine(agent.actor.g)
+
+	if agent.double {
+		agent.picker = agent.NN.withBatch(agent.NN.batchSize())
+		if _, err := agent.picker.consPred(); err != nil {
+			panic(err)
+		}
+		agent.pickerVM = gorgonia.NewTapeMachine(agent.picker.g)
+	}
+
+	// The target network starts out as an exact copy
+	if _, err := agent.Target.consPred(); err != nil {
+		panic(err)
+	}
+	agent.targetVM = gorgonia.NewTapeMachine(agent.Target.g)
+	agent.Target.blend(agent.NN, 1)
 }
 
-func (agent *DQN) PredictQValue(gameState [11]float32) (float32, error) {
-	agent.NN.Let1(gameState)
-	if err := agent.VM.RunAll(); err != nil {
-		log.Printf("Got an error on VM Run %v", err)
-		return 0, err
+// The Q-value of each action in a state
+func (agent *DQN) QValues(gameState Observation) ([]float32, error) {
+	values, err := predict(agent.actor, agent.actorVM, []Observation{gameState})
+	if err != nil {
+		return nil, err
 	}
-	agent.VM.Reset()
-	retVal := agent.NN.predVal.Data().([]float32)[0]
-	return retVal, nil
+	return values[0], nil
 }
 
-func (agent *DQN) BestMove() Vector {
-	var action Vector
-	moves := getPossibleActions(agent.game)
+// Runs a batch of states through a brain in one go, returning the Q-value of
+// each action in each of them
+func predict(nn *Brain, vm gorgonia.VM, gameStates []Observation) ([][]float32, error) {
+	nn.Let1(gameStates)
+	if err := vm.RunAll(); err != nil {
+		log.Printf("Got an error on VM Run %v", err)
+		return nil, err
+	}
+	vm.Reset()
+
+	return rows(nn.predVal.Data().([]float32), len(gameStates), nn.y.Shape()[1]), nil
+}
 
-	if len(moves) < 1 && !agent.game.GameOver() {
-		panic("No possible moves")
+// Copies the first n rows out of a row-major matrix of the given width
+func rows(data []float32, n, width int) [][]float32 {
+	retVal := make([][]float32, n)
+	for i := range retVal {
+		retVal[i] = append([]float32(nil), data[i*width:(i+1)*width]...)
 	}
+	return retVal
+}
 
-	if len(moves) > 0 {
-		action = agent.BestAction(moves)
-	} else {
-		log.Print("We reached a terminal state")
-		log.Printf("Defaulting to move in current direction")
-		action = agent.game.CurrentDirection()
+// Catches the target network up with NN after a training step
+func (agent *DQN) updateTarget() {
+	agent.steps++
+
+	if agent.tau > 0 {
+		agent.Target.blend(agent.NN, agent.tau)
+	} else if agent.steps%agent.targetSync == 0 {
+		agent.Target.blend(agent.NN, 1)
+		agent.syncs++
 	}
+}
 
-	return action
+func (agent *DQN) BestMove(state Observation) Action {
+	return agent.BestAction(state, agent.env.LegalActions())
 }
 
-func (agent *DQN) Train() (err error) {
+func (agent *DQN) Train(episodes int) (err error) {
 	agent.isTraining = true
-	var episodes = 100
 	var games = 50
 	var score float32
 	var gameCount int
-	var totalMoves int
 	var maxGameScore int = 0
 
+	state := agent.env.Reset()
+
+	if agent.tau > 0 {
+		log.Printf("Training %d episodes, blending the target network at tau %v, double DQN %t", episodes, agent.tau, agent.double)
+	} else {
+		log.Printf("Training %d episodes, syncing the target network every %d steps, double DQN %t", episodes, agent.targetSync, agent.double)
+	}
+
+	if pb, ok := agent.Memories.(*PrioritizedBuffer); ok {
+		log.Printf("Replaying memories by priority, alpha %v, beta %v", pb.alpha, pb.Beta())
+	}
+
 	for e := 0; e < episodes; e++ {
 		if e%100 == 0 && e > 99 {
-			log.Printf("Episode %d, max game score %d", e, maxGameScore)
+			log.Printf("Episode %d, max game score %d, training steps %d, target syncs %d", e, maxGameScore, agent.steps, agent.syncs)
+			log.Printf("Memories %d/%d, added %d, evicted %d", agent.Memories.Len(), agent.Memories.Cap(), agent.Memories.Added(), agent.Memories.Evicted())
 		}
 
 		gameCount = 0
-		totalMoves = 0
 		for gameCount < games {
+			action := agent.BestAction(state, agent.env.LegalActions())
 
-			if totalMoves > 10000 {
-				if agent.game.Score() > maxGameScore {
-					maxGameScore = agent.game.Score()
-				}
-				agent.game.Reset()
-				gameCount++
-				continue
-			}
+			nextState, reward, isDone, info := agent.env.Step(action)
+			score = score + reward
 
-			state := agent.game.CurrentState()
-			moves := getPossibleActions(agent.game)
+			mem := Memory{State: state, Action: action, Reward: reward, NextState: nextState, NextMoves: agent.env.LegalActions(), isDone: isDone, scored: info.Scored, died: info.Died}
+			agent.Memories.Add(mem)
 
-			// No possible moves means the game is over now, or in the next step
-			if len(moves) < 1 {
-				log.Printf("No possible moves, game over: %t", agent.game.GameOver())
-				if agent.game.Score() > maxGameScore {
-					maxGameScore = agent.game.Score()
-				}
-				agent.game.Reset()
-				gameCount++
-				continue
+			if info.Score > maxGameScore {
+				maxGameScore = info.Score
 			}
 
-			// TODO use target network to predict Q values and train on separate network
-			action := agent.BestAction(moves)
-
-			reward, isDone := agent.game.EvaluateAction(action)
-			score = score + reward
-
-			agent.game.Move(action)
-			totalMoves++
-
+			state = nextState
 			if isDone {
-				if agent.game.Score() > maxGameScore {
-					maxGameScore = agent.game.Score()
-				}
-				agent.game.Reset()
+				state = agent.env.Reset()
 				gameCount++
 			}
-
-			nextMoves := getPossibleActions(agent.game)
-			futurePossibleStates := getPossibleStates(agent.game, nextMoves)
-			mem := Memory{State: state, Action: action, Reward: reward, NextState: agent.game.CurrentState(), NextMovables: futurePossibleStates, isDone: isDone}
-			agent.Memories = append(agent.Memories, mem)
 		}
 
-		if err := agent.Replay(32); err != nil {
+		if err := agent.Replay(); err != nil {
 			log.Printf("Got an error on replay %v", err)
 			return err
 		}
@@ -145,64 +178,73 @@ func (agent *DQN) Train() (err error) {
 	}
 
 	agent.isTraining = false
-	agent.game.Reset()
+	agent.env.Reset()
 
 	log.Printf("Training complete. Max game score %d", maxGameScore)
 
 	return nil
 }
 
-func (agent *DQN) Replay(batchsize int) error {
-	var totalMemories int = len(agent.Memories)
+// Trains NN on a minibatch of memories, in a single pass
+func (agent *DQN) Replay() error {
 	var totalScoringMoves, totalTerminalMoves int = 0, 0
-	var N int
-	if batchsize < len(agent.Memories) {
-		N = batchsize
-	} else {
-		N = len(agent.Memories)
-	}
-	mems := make([]Memory, N)
-	r := rand.New(rand.NewSource(time.Now().Unix()))
 
-	// Select N random memories from the Q-Table
-	for i := range mems {
-		mems[i] = agent.Memories[r.Intn(totalMemories-i)]
+	// Select up to a batch of memories from the Q-Table
+	batch := agent.Memories.Sample(agent.NN.batchSize())
+	n := len(batch.Memories)
+	if n == 0 {
+		return nil
 	}
 
-	for b := 0; b < batchsize; b++ {
-		mem := mems[b]
-		if mem.Reward == 100 {
+	states := make([]Observation, n)
+	actions := make([]Action, n)
+	nextStates := make([]Observation, n)
+	nextMoves := make([][]Action, n)
+	for i, mem := range batch.Memories {
+		if mem.scored {
 			totalScoringMoves++
-		} else if mem.Reward == -100 {
+		} else if mem.died {
 			totalTerminalMoves++
 		}
+		states[i], actions[i], nextStates[i], nextMoves[i] = mem.State, mem.Action, mem.NextState, mem.NextMoves
+	}
 
-		var y float32
-		if mem.isDone {
-			y = mem.Reward
-		} else {
-			var nextRewards []float32
-			for _, futureState := range mem.NextMovables {
-				nextReward, err := agent.PredictQValue(futureState)
-				if err != nil {
-					return err
-				}
-				nextRewards = append(nextRewards, nextReward)
-			}
-			reward := max(nextRewards)
-			y = mem.Reward + agent.gamma*reward
-		}
-		// Update the NN Graph and Set input state x and the max the target value y for the action we took.
-		agent.NN.Let2(mem.State, y)
+	futureRewards, err := agent.bootstrap(nextStates, nextMoves)
+	if err != nil {
+		return err
+	}
 
-		// Run the NN Graph Calcs to get the predicted target value
-		if err := agent.VM.RunAll(); err != nil {
-			return err
-		}
-		agent.VM.Reset()
-		if err := agent.Solver.Step(agent.NN.model()); err != nil {
-			return err
+	ys := make([]float32, n)
+	for i, mem := range batch.Memories {
+		ys[i] = mem.Reward
+		if !mem.isDone {
+			ys[i] += agent.gamma * futureRewards[i]
 		}
+	}
+
+	// Update the NN Graph and Set input states x and the target values y for the actions we took.
+	agent.NN.Let2(states, actions, ys, batch.Weights)
+
+	// Run the NN Graph Calcs to get the predicted target values
+	if err := agent.VM.RunAll(); err != nil {
+		return err
+	}
+	agent.VM.Reset()
+
+	preds := rows(agent.NN.predVal.Data().([]float32), n, agent.NN.y.Shape()[1])
+	tdErrors := make([]float32, n)
+	for i := range tdErrors {
+		tdErrors[i] = ys[i] - preds[i][actions[i]]
+	}
+
+	if err := agent.Solver.Step(agent.NN.model()); err != nil {
+		return err
+	}
+	agent.updateTarget()
+	agent.Memories.Update(batch.Indices, tdErrors)
+
+	// Exploration wears off with every memory learnt from
+	for range batch.Memories {
 		if agent.epsilon > agent.epsDecayMin {
 			agent.epsilon *= agent.decay
 		}
@@ -211,64 +253,77 @@ func (agent *DQN) Replay(batchsize int) error {
 	return nil
 }
 
-func (agent *DQN) BestAction(moves []Vector) (bestAction Vector) {
-
-	// If we're not training, strip use heuristic to avoid terminal actions
-	if !agent.isTraining {
-		nonTerminalMoves := agent.StripTerminalActions(moves)
+// The value of the best of the legal actions in each of the next states. The
+// target network values them, and with double DQN it's NN that picks the best
+// action, to keep a single network from overestimating its own favourite.
+func (agent *DQN) bootstrap(nextStates []Observation, nextMoves [][]Action) ([]float32, error) {
+	targetValues, err := predict(agent.Target, agent.targetVM, nextStates)
+	if err != nil {
+		return nil, err
+	}
 
-		if len(nonTerminalMoves) > 0 {
-			moves = nonTerminalMoves
+	var values [][]float32
+	if agent.double {
+		if values, err = predict(agent.picker, agent.pickerVM, nextStates); err != nil {
+			return nil, err
 		}
 	}
 
-	if len(moves) < 1 {
-		panic("bestAction called with no moves")
+	retVal := make([]float32, len(nextStates))
+	for i := range retVal {
+		if agent.double {
+			retVal[i] = targetValues[i][argmax(values[i], nextMoves[i])]
+		} else {
+			retVal[i] = targetValues[i][argmax(targetValues[i], nextMoves[i])]
+		}
 	}
+	return retVal, nil
+}
+
+// Picks the action with the highest Q-value in state, or with probability
+// epsilon a random one of moves
+func (agent *DQN) BestAction(state Observation, moves []Action) (bestAction Action) {
 
-	var bestActions []Vector = make([]Vector, 0)
-	var maxActValue float32 = -100
+	// If we're not training, strip use heuristic to avoid terminal actions
+	if !agent.isTraining && agent.sim != nil {
+		nonTerminalMoves := agent.StripTerminalActions(moves)
 
-	for _, a := range moves {
-		nextState := agent.game.NextState(a)
+		if len(nonTerminalMoves) > 0 {
+			moves = nonTerminalMoves
+		}
 
 		// If we're not training, use heuristic to gaurantee scoring moves
-		if !agent.isTraining {
-			reward, _ := agent.game.EvaluateAction(a)
-			if reward == 100 {
+		for _, a := range moves {
+			if _, _, _, info := agent.sim.Peek(a); info.Scored {
 				return a
 			}
 		}
+	}
 
-		actionValue, err := agent.PredictQValue(nextState)
+	if len(moves) < 1 {
+		panic("bestAction called with no moves")
+	}
 
-		if err != nil {
-			panic(err)
-		}
-		if actionValue > maxActValue {
-			bestAction = a
-			maxActValue = actionValue
-			bestActions = append(bestActions, a)
-		} else if actionValue == maxActValue {
-			bestActions = append(bestActions, a)
-		}
+	// Exploring is for training. A trained agent, epsilon and all, plays
+	// what it has learnt.
+	if agent.isTraining && agent.rng.Float32() < agent.epsilon {
+		return moves[agent.rng.Intn(len(moves))]
 	}
 
-	if rand.Float32() < agent.epsilon && len(bestActions) > 1 {
-		r := rand.New(rand.NewSource(time.Now().Unix()))
-		randomAction := bestActions[r.Intn(len(bestActions))]
-		return randomAction
+	actionValues, err := agent.QValues(state)
+	if err != nil {
+		panic(err)
 	}
 
-	return bestAction
+	return argmax(actionValues, moves)
 }
 
-func (agent *DQN) StripTerminalActions(actions []Vector) []Vector {
-	var retVal []Vector
+func (agent *DQN) StripTerminalActions(actions []Action) []Action {
+	var retVal []Action
 
 	for _, a := range actions {
-		reward, _ := agent.game.EvaluateAction(a)
-		if reward != -100 {
+		_, _, _, info := agent.sim.Peek(a)
+		if !info.Died {
 			retVal = append(retVal, a)
 		}
 	}
@@ -276,45 +331,24 @@ func (agent *DQN) StripTerminalActions(actions []Vector) []Vector {
 	return retVal
 }
 
-func getPossibleActions(g *snake.Game) (retVal []Vector) {
-	if g.GameOver() {
-		return retVal
-	}
-
-	for i := range cardinals {
-		if g.MoveIsValid(cardinals[i]) {
-			retVal = append(retVal, cardinals[i])
+// The one of moves with the highest value, or the index of the highest value
+// when moves is empty
+func argmax(a []float32, moves []Action) Action {
+	if len(moves) == 0 {
+		var retVal Action
+		for i := range a {
+			if a[i] > a[retVal] {
+				retVal = Action(i)
+			}
 		}
-	}
-
-	return retVal
-}
-
-func getPossibleStates(g *snake.Game, moves []Vector) (retVal [][11]float32) {
-	if g.GameOver() {
 		return retVal
 	}
 
-	for _, m := range moves {
-		retVal = append(retVal, g.NextState(m))
-	}
-	return retVal
-}
-
-func max(a []float32) float32 {
-	var m float32 = -999999999
-	for i := range a {
-		if a[i] > m {
-			m = a[i]
+	retVal := moves[0]
+	for _, m := range moves[1:] {
+		if a[m] > a[retVal] {
+			retVal = m
 		}
 	}
-	return m
-}
-
-func truncateMemories(memories []Memory, num int) []Memory {
-	max := len(memories) - num
-	if max > 0 && len(memories) > max {
-		return memories[:max-1]
-	}
-	return memories
+	return retVal
 }
@@ -1,14 +1,229 @@
 package agent
 
 import (
+	"math"
+	"math/rand"
+
 	. "github.com/casen/snakegame/model"
 )
 
 type Memory struct {
-	State        [11]float32
-	Action       Vector
-	Reward       float32
-	NextState    [11]float32
-	NextMovables [][11]float32
-	isDone       bool
+	State     Observation
+	Action    Action
+	Reward    float32
+	NextState Observation
+	NextMoves []Action // the legal actions in NextState
+	isDone    bool
+	scored    bool
+	died      bool
+}
+
+// Buffer stores memories for replay
+type Buffer interface {
+	Add(m Memory)
+
+	// Sample picks up to n memories to learn from
+	Sample(n int) Batch
+
+	// Update tells the buffer how far off the brain was on each memory of the
+	// last batch, indices being Batch.Indices
+	Update(indices []int, tdErrors []float32)
+
+	Len() int
+	Cap() int
+	Added() int
+	Evicted() int
+}
+
+// Batch is a sample of memories, along with where they sit in the buffer and
+// how much each should weigh in the loss
+type Batch struct {
+	Memories []Memory
+	Indices  []int
+	Weights  []float32
+}
+
+// ReplayBuffer holds the most recent memories up to a fixed capacity. Once it
+// is full every new memory evicts the oldest, so it never grows past capacity
+// however long training runs.
+type ReplayBuffer struct {
+	memories []Memory
+	next     int // index the next memory is written to once full
+	added    int
+	evicted  int
+	rng      *rand.Rand
+}
+
+func NewReplayBuffer(capacity int, rng *rand.Rand) *ReplayBuffer {
+	return &ReplayBuffer{
+		memories: make([]Memory, 0, capacity),
+		rng:      rng,
+	}
+}
+
+func (rb *ReplayBuffer) Add(m Memory) {
+	rb.store(m)
+}
+
+// Stores the memory and returns the index it went to
+func (rb *ReplayBuffer) store(m Memory) int {
+	rb.added++
+
+	if len(rb.memories) < cap(rb.memories) {
+		rb.memories = append(rb.memories, m)
+		return len(rb.memories) - 1
+	}
+
+	i := rb.next
+	rb.memories[i] = m
+	rb.next = (rb.next + 1) % len(rb.memories)
+	rb.evicted++
+	return i
+}
+
+// Sample picks n different memories, each one equally likely and weighing
+// the same. When the buffer holds n or fewer memories, it returns all of them.
+func (rb *ReplayBuffer) Sample(n int) Batch {
+	total := len(rb.memories)
+	n = min(n, total)
+
+	var indices []int
+	if n == total {
+		indices = make([]int, total)
+		for i := range indices {
+			indices[i] = i
+		}
+	} else {
+		// Robert Floyd's algorithm, so we never need to shuffle the whole buffer
+		chosen := make(map[int]bool, n)
+		for j := total - n; j < total; j++ {
+			i := rb.rng.Intn(j + 1)
+			if chosen[i] {
+				i = j
+			}
+			chosen[i] = true
+			indices = append(indices, i)
+		}
+	}
+
+	return rb.batch(indices, nil)
+}
+
+// Update does nothing, every memory is as likely as any other
+func (rb *ReplayBuffer) Update(indices []int, tdErrors []float32) {}
+
+// Gathers the memories at indices into a batch. Without weights, every memory
+// weighs 1.
+func (rb *ReplayBuffer) batch(indices []int, weights []float32) Batch {
+	batch := Batch{
+		Memories: make([]Memory, len(indices)),
+		Indices:  indices,
+		Weights:  weights,
+	}
+	for i, idx := range indices {
+		batch.Memories[i] = rb.memories[idx]
+	}
+	if batch.Weights == nil {
+		batch.Weights = make([]float32, len(indices))
+		for i := range batch.Weights {
+			batch.Weights[i] = 1
+		}
+	}
+	return batch
+}
+
+// Len is the number of memories in the buffer
+func (rb *ReplayBuffer) Len() int { return len(rb.memories) }
+
+// Cap is the most memories the buffer will hold
+func (rb *ReplayBuffer) Cap() int { return cap(rb.memories) }
+
+// Added is the number of memories ever added to the buffer
+func (rb *ReplayBuffer) Added() int { return rb.added }
+
+// Evicted is the number of memories pushed out to make room for newer ones
+func (rb *ReplayBuffer) Evicted() int { return rb.evicted }
+
+// PrioritizedBuffer replays memories in proportion to how surprising they
+// were, measured by their last TD error. Rare events like eating or dying
+// otherwise drown in the many ordinary moves. The bias this brings in is
+// corrected with importance sampling weights, fully so once beta reaches 1.
+type PrioritizedBuffer struct {
+	ReplayBuffer
+	tree        *sumTree
+	alpha       float64 // how much priorities count, 0 is uniform
+	beta        float64 // how much the sampling bias is corrected, annealed towards 1
+	betaStep    float64 // how much beta grows per sampled memory
+	maxPriority float64 // new memories get the highest priority seen, so each is replayed at least once
+}
+
+// Priorities are never quite 0, so every memory keeps a chance of being replayed
+const priorityEpsilon = 1e-3
+
+// Creates a prioritized buffer whose beta grows from betaStart to 1 over the
+// first betaSteps sampled memories
+func NewPrioritizedBuffer(capacity int, alpha, betaStart float32, betaSteps int, rng *rand.Rand) *PrioritizedBuffer {
+	betaStep := 0.0
+	if betaSteps > 0 {
+		betaStep = (1 - float64(betaStart)) / float64(betaSteps)
+	}
+
+	return &PrioritizedBuffer{
+		ReplayBuffer: *NewReplayBuffer(capacity, rng),
+		tree:         newSumTree(capacity),
+		alpha:        float64(alpha),
+		beta:         float64(betaStart),
+		betaStep:     betaStep,
+		maxPriority:  1,
+	}
+}
+
+func (pb *PrioritizedBuffer) Add(m Memory) {
+	i := pb.store(m)
+	pb.tree.set(i, math.Pow(pb.maxPriority, pb.alpha))
+}
+
+// Sample picks n memories in proportion to their priority, one from each of n
+// equal slices of the total priority. A memory can be picked more than once.
+func (pb *PrioritizedBuffer) Sample(n int) Batch {
+	total := len(pb.memories)
+	if total == 0 {
+		return Batch{}
+	}
+
+	indices := make([]int, n)
+	weights := make([]float32, n)
+	segment := pb.tree.total() / float64(n)
+
+	var maxWeight float64
+	for i := range indices {
+		v := segment * (float64(i) + pb.rng.Float64())
+		idx := min(pb.tree.find(v), total-1)
+		indices[i] = idx
+
+		p := pb.tree.get(idx) / pb.tree.total()
+		w := math.Pow(float64(total)*p, -pb.beta)
+		weights[i] = float32(w)
+		maxWeight = math.Max(maxWeight, w)
+	}
+
+	// Weights only ever scale the loss down
+	for i := range weights {
+		weights[i] /= float32(maxWeight)
+	}
+
+	pb.beta = math.Min(1, pb.beta+pb.betaStep*float64(n))
+
+	return pb.batch(indices, weights)
 }
+
+func (pb *PrioritizedBuffer) Update(indices []int, tdErrors []float32) {
+	for i, idx := range indices {
+		priority := math.Abs(float64(tdErrors[i])) + priorityEpsilon
+		pb.maxPriority = math.Max(pb.maxPriority, priority)
+		pb.tree.set(idx, math.Pow(priority, pb.alpha))
+	}
+}
+
+// Beta is how much the sampling bias is corrected at the moment
+func (pb *PrioritizedBuffer) Beta() float32 { return float32(pb.beta) }
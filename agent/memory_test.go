@@ -0,0 +1,163 @@
+package agent
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/casen/snakegame/model"
+)
+
+// Memories are told apart by their reward
+func rewardMemory(i int) Memory {
+	return Memory{Reward: float32(i)}
+}
+
+func TestReplayBufferEvictsOldest(t *testing.T) {
+	rb := NewReplayBuffer(3, rand.New(rand.NewSource(1)))
+	for i := 0; i < 5; i++ {
+		rb.Add(rewardMemory(i))
+	}
+
+	if rb.Len() != 3 || rb.Cap() != 3 {
+		t.Errorf("Len(), Cap() = %d, %d; want 3, 3", rb.Len(), rb.Cap())
+	}
+	if rb.Added() != 5 || rb.Evicted() != 2 {
+		t.Errorf("Added(), Evicted() = %d, %d; want 5, 2", rb.Added(), rb.Evicted())
+	}
+
+	got := map[float32]bool{}
+	for _, m := range rb.Sample(3).Memories {
+		got[m.Reward] = true
+	}
+	for _, want := range []float32{2, 3, 4} {
+		if !got[want] {
+			t.Errorf("Sample(3) = %v; want memories 2, 3 and 4", got)
+		}
+	}
+}
+
+func TestReplayBufferSampleWithoutReplacement(t *testing.T) {
+	rb := NewReplayBuffer(100, rand.New(rand.NewSource(1)))
+	for i := 0; i < 50; i++ {
+		rb.Add(rewardMemory(i))
+	}
+
+	// More than we have hands back everything, rather than indexing past the end
+	if got := len(rb.Sample(80).Memories); got != 50 {
+		t.Errorf("len(Sample(80)) = %d; want 50", got)
+	}
+
+	counts := make([]int, 50)
+	for round := 0; round < 2000; round++ {
+		seen := map[float32]bool{}
+		for _, m := range rb.Sample(10).Memories {
+			if seen[m.Reward] {
+				t.Fatalf("Sample(10) picked memory %v twice", m.Reward)
+			}
+			seen[m.Reward] = true
+			counts[int(m.Reward)]++
+		}
+	}
+
+	// Each memory is expected 400 times, the oldest shouldn't be favoured
+	for i, c := range counts {
+		if c < 300 || c > 500 {
+			t.Errorf("memory %d sampled %d times; want about 400", i, c)
+		}
+	}
+}
+
+func TestReplayBufferStaysBounded(t *testing.T) {
+	rb := NewReplayBuffer(10, rand.New(rand.NewSource(1)))
+	for i := 0; i < 10000; i++ {
+		rb.Add(Memory{State: model.Observation{float32(i)}})
+	}
+
+	if len(rb.memories) != 10 || cap(rb.memories) != 10 {
+		t.Errorf("buffer grew to len %d, cap %d; want 10, 10", len(rb.memories), cap(rb.memories))
+	}
+}
+
+func TestSumTreeFind(t *testing.T) {
+	tree := newSumTree(5)
+	for i, p := range []float64{1, 0, 3, 2, 4} {
+		tree.set(i, p)
+	}
+
+	if tree.total() != 10 {
+		t.Errorf("total() = %v; want 10", tree.total())
+	}
+
+	testCases := []struct {
+		v    float64
+		want int
+	}{
+		{0, 0}, {0.5, 0}, {1, 2}, {3.9, 2}, {4, 3}, {5.9, 3}, {6, 4}, {9.9, 4},
+	}
+	for _, tc := range testCases {
+		if got := tree.find(tc.v); got != tc.want {
+			t.Errorf("find(%v) = %d; want %d", tc.v, got, tc.want)
+		}
+	}
+
+	tree.set(2, 0)
+	if tree.total() != 7 || tree.get(2) != 0 {
+		t.Errorf("after set(2, 0) total(), get(2) = %v, %v; want 7, 0", tree.total(), tree.get(2))
+	}
+}
+
+func TestPrioritizedBufferFavoursSurprises(t *testing.T) {
+	pb := NewPrioritizedBuffer(10, 1, 0.4, 0, rand.New(rand.NewSource(1)))
+	var indices []int
+	for i := 0; i < 10; i++ {
+		pb.Add(rewardMemory(i))
+		indices = append(indices, i)
+	}
+
+	// Memory 7 was 9 times as surprising as each of the others
+	tdErrors := make([]float32, 10)
+	for i := range tdErrors {
+		tdErrors[i] = 1
+	}
+	tdErrors[7] = 9
+	pb.Update(indices, tdErrors)
+
+	var sevens, total int
+	for round := 0; round < 1000; round++ {
+		batch := pb.Sample(10)
+		for i, m := range batch.Memories {
+			total++
+			if m.Reward == 7 {
+				sevens++
+			}
+			if batch.Weights[i] <= 0 || batch.Weights[i] > 1 {
+				t.Fatalf("weight %v of memory %v; want in (0, 1]", batch.Weights[i], m.Reward)
+			}
+			if int(m.Reward) != batch.Indices[i] {
+				t.Fatalf("memory %v came with index %d", m.Reward, batch.Indices[i])
+			}
+		}
+	}
+
+	// Half of the total priority sits on memory 7
+	if share := float64(sevens) / float64(total); share < 0.45 || share > 0.55 {
+		t.Errorf("memory 7 was %.2f of the samples; want about 0.5", share)
+	}
+}
+
+func TestPrioritizedBufferAnnealsBeta(t *testing.T) {
+	pb := NewPrioritizedBuffer(10, 0.6, 0.4, 100, rand.New(rand.NewSource(1)))
+	for i := 0; i < 10; i++ {
+		pb.Add(rewardMemory(i))
+	}
+
+	pb.Sample(50)
+	if got := pb.Beta(); got < 0.69 || got > 0.71 {
+		t.Errorf("Beta() after 50 of 100 steps = %v; want 0.7", got)
+	}
+
+	pb.Sample(100)
+	if got := pb.Beta(); got != 1 {
+		t.Errorf("Beta() after 150 of 100 steps = %v; want 1", got)
+	}
+}
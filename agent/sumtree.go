@@ -0,0 +1,58 @@
+package agent
+
+// sumTree is a binary tree whose leaves hold priorities and whose inner nodes
+// hold the sum of the leaves below them. That lets us pick a leaf in proportion
+// to its priority, and change a priority, in O(log n).
+type sumTree struct {
+	nodes    []float64 // nodes[0] is the root, the leaves start at capacity-1
+	capacity int       // a power of two, so every leaf sits at the same depth and in order
+}
+
+func newSumTree(size int) *sumTree {
+	capacity := 1
+	for capacity < size {
+		capacity *= 2
+	}
+
+	return &sumTree{
+		nodes:    make([]float64, 2*capacity-1),
+		capacity: capacity,
+	}
+}
+
+// Sets the priority of leaf i
+func (t *sumTree) set(i int, priority float64) {
+	node := i + t.capacity - 1
+	change := priority - t.nodes[node]
+
+	t.nodes[node] = priority
+	for node > 0 {
+		node = (node - 1) / 2
+		t.nodes[node] += change
+	}
+}
+
+// The priority of leaf i
+func (t *sumTree) get(i int) float64 {
+	return t.nodes[i+t.capacity-1]
+}
+
+// The sum of all priorities
+func (t *sumTree) total() float64 {
+	return t.nodes[0]
+}
+
+// Finds the leaf where the running sum of priorities, from the left, passes v
+func (t *sumTree) find(v float64) int {
+	node := 0
+	for node < t.capacity-1 {
+		left := 2*node + 1
+		if v < t.nodes[left] || t.nodes[left+1] == 0 {
+			node = left
+		} else {
+			v -= t.nodes[left]
+			node = left + 1
+		}
+	}
+	return node - (t.capacity - 1)
+}
@@ -0,0 +1,235 @@
+package battlesnake
+
+import (
+	"fmt"
+
+	"github.com/casen/snakegame/model"
+	"github.com/casen/snakegame/snake"
+)
+
+// The JSON the Battlesnake engine and a snake server talk in, see
+// https://docs.battlesnake.com/api
+
+// Coord is a cell of a Battlesnake board. X runs left to right and Y bottom to
+// top, so the top-left cell of our boards is (0, height-1).
+type Coord struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type Battlesnake struct {
+	ID      string  `json:"id"`
+	Name    string  `json:"name"`
+	Health  int     `json:"health"`
+	Body    []Coord `json:"body"` // head first, the tail cell repeated while the snake grows
+	Head    Coord   `json:"head"`
+	Length  int     `json:"length"`
+	Latency string  `json:"latency"`
+	Shout   string  `json:"shout"`
+}
+
+type Board struct {
+	Height  int           `json:"height"`
+	Width   int           `json:"width"`
+	Food    []Coord       `json:"food"`
+	Hazards []Coord       `json:"hazards"`
+	Snakes  []Battlesnake `json:"snakes"`
+}
+
+type Ruleset struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type Game struct {
+	ID      string  `json:"id"`
+	Ruleset Ruleset `json:"ruleset"`
+	Map     string  `json:"map"`
+	Timeout int     `json:"timeout"` // milliseconds a move may take
+	Source  string  `json:"source"`
+}
+
+// GameState is what the engine sends with every request but the first
+type GameState struct {
+	Game  Game        `json:"game"`
+	Turn  int         `json:"turn"`
+	Board Board       `json:"board"`
+	You   Battlesnake `json:"you"`
+}
+
+type MoveResponse struct {
+	Move  string `json:"move"`
+	Shout string `json:"shout,omitempty"`
+}
+
+// InfoResponse is how the snake introduces itself to the engine
+type InfoResponse struct {
+	APIVersion string `json:"apiversion"`
+	Author     string `json:"author,omitempty"`
+	Color      string `json:"color,omitempty"`
+	Head       string `json:"head,omitempty"`
+	Tail       string `json:"tail,omitempty"`
+	Version    string `json:"version,omitempty"`
+}
+
+// MaxHealth is the health of a snake that just ate. It drops by one every
+// move, and the snake starves when it runs out.
+const MaxHealth = 100
+
+// The ruleset whose board wraps around, the rest have solid walls
+const wrappedRuleset = "wrapped"
+
+// The moves a snake can answer with, Y pointing up the board
+var moves = map[string]model.Vector{
+	"up":    {X: -1, Y: 0},
+	"down":  {X: 1, Y: 0},
+	"left":  {X: 0, Y: -1},
+	"right": {X: 0, Y: 1},
+}
+
+// MoveName is the Battlesnake move for a direction on our boards. A snake
+// with no direction to keep to goes up.
+func MoveName(dir model.Vector) string {
+	for name, d := range moves {
+		if d == dir {
+			return name
+		}
+	}
+	return "up"
+}
+
+// MoveDirection is the direction on our boards of a Battlesnake move
+func MoveDirection(name string) (model.Vector, bool) {
+	dir, ok := moves[name]
+	return dir, ok
+}
+
+// Rules are the standard Battlesnake rules on top of cfg: every food is
+// normal food, at least one is always on the board and another turns up with
+// a 15% chance every turn, and a snake starves once its health runs out.
+func Rules(cfg snake.Config) snake.Config {
+	food := snake.FoodRules[snake.NormalFood]
+	food.Spawn = snake.SpawnPolicy{Min: 1, Max: cfg.Rows * cfg.Cols, Chance: 0.15}
+	cfg.Foods = []snake.FoodRule{food}
+	cfg.FoodSpots = nil
+	cfg.Growth = 1
+	cfg.Starvation = snake.StarveRule{Limit: MaxHealth - 1}
+	return cfg
+}
+
+// Position is the board of the state as a position on our boards, seen from
+// the snake the state was sent to. Hazards only cost health in Battlesnake,
+// but our snakes have no notion of that, so they're obstacles to keep off.
+func (s GameState) Position() (snake.Position, error) {
+	b := s.Board
+	p := snake.Position{Rows: b.Height, Cols: b.Width}
+	if s.Game.Ruleset.Name == wrappedRuleset {
+		p.Walls = snake.WrapAround
+	}
+
+	you, err := s.snake(s.You)
+	if err != nil {
+		return snake.Position{}, err
+	}
+	p.Snakes = append(p.Snakes, you)
+	for _, bs := range b.Snakes {
+		if bs.ID == s.You.ID {
+			continue
+		}
+		other, err := s.snake(bs)
+		if err != nil {
+			return snake.Position{}, err
+		}
+		p.Snakes = append(p.Snakes, other)
+	}
+
+	for _, c := range b.Food {
+		p.Foods = append(p.Foods, snake.Food{Kind: snake.NormalFood, At: s.point(c)})
+	}
+	// Hazards stack up in some maps, an obstacle is there once
+	seen := make(map[model.Point]bool, len(b.Hazards))
+	for _, c := range b.Hazards {
+		if q := s.point(c); !seen[q] {
+			seen[q] = true
+			p.Obstacles = append(p.Obstacles, q)
+		}
+	}
+
+	return p, nil
+}
+
+func (s GameState) point(c Coord) model.Point {
+	return model.Point{X: s.Board.Height - 1 - c.Y, Y: c.X}
+}
+
+func (s GameState) coord(p model.Point) Coord {
+	return Coord{X: p.Y, Y: s.Board.Height - 1 - p.X}
+}
+
+// The snake as it is on our boards, tail to head. The repeated tail cells of a
+// growing snake are cells it still has to grow.
+func (s GameState) snake(bs Battlesnake) (snake.SnakePosition, error) {
+	if len(bs.Body) == 0 {
+		return snake.SnakePosition{}, fmt.Errorf("snake %s has no body", bs.ID)
+	}
+
+	sp := snake.SnakePosition{Hunger: MaxHealth - bs.Health}
+	for i := len(bs.Body) - 1; i >= 0; i-- {
+		p := s.point(bs.Body[i])
+		if len(sp.Body) > 0 && sp.Body[len(sp.Body)-1] == p {
+			if len(sp.Body) > 1 {
+				return snake.SnakePosition{}, fmt.Errorf("snake %s doubles back on itself", bs.ID)
+			}
+			sp.Growing++
+			continue
+		}
+		sp.Body = append(sp.Body, p)
+	}
+
+	if n := len(sp.Body); n > 1 {
+		sp.Direction = snake.Heading(sp.Body[n-2], sp.Body[n-1])
+	} else {
+		// At the start of a game every segment is stacked on the head, so
+		// there's no heading to go by. The snake is taken to head away from
+		// the nearest wall, so it's only kept from turning back towards it.
+		sp.Direction = s.awayFromWalls(sp.Body[0])
+	}
+
+	return sp, nil
+}
+
+// The direction from p away from the nearest edge of the board
+func (s GameState) awayFromWalls(p model.Point) model.Vector {
+	rows, cols := s.Board.Height, s.Board.Width
+	walls := []struct {
+		distance int
+		away     model.Vector
+	}{
+		{p.X, model.Vector{X: 1, Y: 0}},
+		{rows - 1 - p.X, model.Vector{X: -1, Y: 0}},
+		{p.Y, model.Vector{X: 0, Y: 1}},
+		{cols - 1 - p.Y, model.Vector{X: 0, Y: -1}},
+	}
+
+	nearest := walls[0]
+	for _, w := range walls[1:] {
+		if w.distance < nearest.distance {
+			nearest = w
+		}
+	}
+	return nearest.away
+}
+
+// The snake of a position as Battlesnake has it, head first
+func (s GameState) battlesnake(id string, sp snake.SnakePosition) Battlesnake {
+	bs := Battlesnake{ID: id, Name: id, Health: MaxHealth - sp.Hunger}
+	for i := len(sp.Body) - 1; i >= 0; i-- {
+		bs.Body = append(bs.Body, s.coord(sp.Body[i]))
+	}
+	for i := 0; i < sp.Growing; i++ {
+		bs.Body = append(bs.Body, bs.Body[len(bs.Body)-1])
+	}
+	bs.Head = bs.Body[0]
+	bs.Length = len(bs.Body)
+	return bs
+}
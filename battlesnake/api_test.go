@@ -0,0 +1,99 @@
+package battlesnake
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/casen/snakegame/model"
+	"github.com/casen/snakegame/snake"
+)
+
+// A 5x4 board with a snake that just ate, a rival that has just started and
+// a hazard, as the engine sends it
+const stateJSON = `{
+	"game": {"id": "test", "ruleset": {"name": "standard"}, "timeout": 500},
+	"turn": 3,
+	"board": {
+		"height": 4,
+		"width": 5,
+		"food": [{"x": 4, "y": 3}],
+		"hazards": [{"x": 2, "y": 2}, {"x": 2, "y": 2}],
+		"snakes": [
+			{"id": "rival", "health": 100, "body": [{"x": 4, "y": 0}, {"x": 4, "y": 0}, {"x": 4, "y": 0}]},
+			{"id": "me", "health": 90, "body": [{"x": 1, "y": 1}, {"x": 0, "y": 1}, {"x": 0, "y": 0}, {"x": 0, "y": 0}]}
+		]
+	},
+	"you": {"id": "me", "health": 90, "body": [{"x": 1, "y": 1}, {"x": 0, "y": 1}, {"x": 0, "y": 0}, {"x": 0, "y": 0}]}
+}`
+
+func TestPosition(t *testing.T) {
+	var state GameState
+	if err := json.Unmarshal([]byte(stateJSON), &state); err != nil {
+		t.Fatalf("Unmarshal() = %v", err)
+	}
+	pos, err := state.Position()
+	if err != nil {
+		t.Fatalf("Position() = %v", err)
+	}
+
+	if pos.Rows != 4 || pos.Cols != 5 || pos.Walls != snake.SolidWalls {
+		t.Errorf("Position() = %dx%d %v; want 4x5 solid walls", pos.Rows, pos.Cols, pos.Walls)
+	}
+	if len(pos.Snakes) != 2 {
+		t.Fatalf("Position() has %d snakes; want 2", len(pos.Snakes))
+	}
+
+	// Y counts up from the bottom, our rows count down from the top
+	me := pos.Snakes[0]
+	wantBody := []model.Point{{X: 3, Y: 0}, {X: 2, Y: 0}, {X: 2, Y: 1}}
+	if !slices.Equal(me.Body, wantBody) || me.Growing != 1 || me.Hunger != 10 {
+		t.Errorf("you = %+v; want body %v, growing 1, hunger 10", me, wantBody)
+	}
+	if me.Direction != (model.Vector{X: 0, Y: 1}) {
+		t.Errorf("you heading %v; want east", me.Direction)
+	}
+
+	rival := pos.Snakes[1]
+	// Coiled up in the bottom right corner, it heads away from the bottom
+	if !slices.Equal(rival.Body, []model.Point{{X: 3, Y: 4}}) || rival.Growing != 2 || rival.Direction != (model.Vector{X: -1, Y: 0}) {
+		t.Errorf("rival = %+v; want coiled up at {3 4} with 2 to grow heading north", rival)
+	}
+
+	if len(pos.Foods) != 1 || pos.Foods[0].At != (model.Point{X: 0, Y: 4}) {
+		t.Errorf("Foods = %v; want one at {0 4}", pos.Foods)
+	}
+	if !slices.Equal(pos.Obstacles, []model.Point{{X: 1, Y: 2}}) {
+		t.Errorf("Obstacles = %v; want the hazard at {1 2} once", pos.Obstacles)
+	}
+}
+
+func TestPositionRoundTrip(t *testing.T) {
+	var state GameState
+	if err := json.Unmarshal([]byte(stateJSON), &state); err != nil {
+		t.Fatalf("Unmarshal() = %v", err)
+	}
+	pos, err := state.Position()
+	if err != nil {
+		t.Fatalf("Position() = %v", err)
+	}
+
+	for i, bs := range []Battlesnake{state.You, state.Board.Snakes[0]} {
+		got := state.battlesnake(bs.ID, pos.Snakes[i])
+		if !slices.Equal(got.Body, bs.Body) || got.Health != bs.Health || got.Head != bs.Body[0] {
+			t.Errorf("battlesnake() = %+v; want %+v", got, bs)
+		}
+	}
+}
+
+func TestMoveNames(t *testing.T) {
+	for _, dir := range snake.Directions {
+		name := MoveName(dir)
+		if got, ok := MoveDirection(name); !ok || got != dir {
+			t.Errorf("MoveDirection(%q) = %v, %t; want %v", name, got, ok, dir)
+		}
+	}
+	if got := MoveName(model.Vector{}); got != "up" {
+		t.Errorf("MoveName() with no direction = %q; want \"up\"", got)
+	}
+}
@@ -0,0 +1,173 @@
+package battlesnake
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/casen/snakegame/model"
+	"github.com/casen/snakegame/snake"
+)
+
+// Referee plays games between Battlesnake servers on our own engine, standing
+// in for the Battlesnake engine so the whole flow can run offline. Each server
+// plays one snake of the config: the first server the config's snake, the
+// rest its rivals in order.
+type Referee struct {
+	// Asks the servers for their moves, its timeout is how long a server may
+	// take over one
+	Client *http.Client
+
+	game   *snake.Game
+	urls   []string
+	played int
+}
+
+// Result is how a game the referee played ended
+type Result struct {
+	Turns  int
+	Winner int           // the player left standing, -1 when nobody is
+	Causes []snake.Cause // why each player died, CauseNone for the winner
+}
+
+// Creates a referee for games between the servers at urls on boards set up by
+// cfg, which needs a rival for every server after the first. Food is placed
+// by cfg.Seed, so the games play out the same as long as the servers do.
+func NewReferee(cfg snake.Config, urls ...string) (*Referee, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("referee needs at least one server")
+	}
+	if len(cfg.Rivals) != len(urls)-1 {
+		return nil, fmt.Errorf("%d servers need %d rivals in the config, got %d", len(urls), len(urls)-1, len(cfg.Rivals))
+	}
+
+	game, err := snake.NewGame(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Referee{
+		Client: &http.Client{Timeout: 500 * time.Millisecond},
+		game:   game,
+		urls:   urls,
+	}, nil
+}
+
+// Play plays a game to the end. The servers hear of it through /start, are
+// asked for a /move every turn while their snake is alive and hear of the end
+// through /end.
+func (r *Referee) Play() (Result, error) {
+	if r.played > 0 {
+		r.game.Reset()
+	}
+	r.played++
+	info := Game{
+		ID:      fmt.Sprintf("local-%d", r.played),
+		Ruleset: Ruleset{Name: "standard", Version: "local"},
+		Map:     "standard",
+		Timeout: int(r.Client.Timeout / time.Millisecond),
+		Source:  "referee",
+	}
+	if r.game.Config().Walls == snake.WrapAround {
+		info.Ruleset.Name = wrappedRuleset
+	}
+
+	for i, url := range r.urls {
+		if err := r.post(url, "/start", r.state(info, 0, i), nil); err != nil {
+			return Result{}, err
+		}
+	}
+
+	turn := 0
+	for ; !r.game.GameOver(); turn++ {
+		actions := make([]model.Vector, len(r.urls))
+		for i, url := range r.urls {
+			if !r.game.Alive(i) {
+				continue
+			}
+			var move MoveResponse
+			if err := r.post(url, "/move", r.state(info, turn, i), &move); err != nil {
+				return Result{}, err
+			}
+			dir, ok := MoveDirection(move.Move)
+			if !ok {
+				return Result{}, fmt.Errorf("%s answered turn %d with the unknown move %q", url, turn, move.Move)
+			}
+			actions[i] = dir
+		}
+		r.game.StepAll(actions)
+	}
+
+	result := Result{Turns: turn, Winner: -1}
+	for i, url := range r.urls {
+		if r.game.Alive(i) {
+			result.Winner = i
+		}
+		result.Causes = append(result.Causes, r.game.CauseOf(i))
+		if err := r.post(url, "/end", r.state(info, turn, i), nil); err != nil {
+			return Result{}, err
+		}
+	}
+	return result, nil
+}
+
+// The game as the engine would send it to the given player
+func (r *Referee) state(info Game, turn, you int) GameState {
+	pos := r.game.Position()
+	state := GameState{
+		Game:  info,
+		Turn:  turn,
+		Board: Board{Height: pos.Rows, Width: pos.Cols},
+	}
+
+	for i, sp := range pos.Snakes {
+		bs := state.battlesnake(fmt.Sprintf("snake-%d", i+1), sp)
+		if i == you {
+			state.You = bs
+		}
+		// Battlesnake takes snakes off the board once they're out
+		if sp.Cause == snake.CauseNone {
+			state.Board.Snakes = append(state.Board.Snakes, bs)
+		}
+	}
+	for _, f := range pos.Foods {
+		state.Board.Food = append(state.Board.Food, state.coord(f.At))
+	}
+	for _, p := range pos.Obstacles {
+		state.Board.Hazards = append(state.Board.Hazards, state.coord(p))
+	}
+
+	return state
+}
+
+// Posts the state to the endpoint of the server at url, decoding the answer
+// into reply unless it's nil
+func (r *Referee) post(url, endpoint string, state GameState, reply any) error {
+	body, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.Client.Post(strings.TrimSuffix(url, "/")+endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var msg bytes.Buffer
+		msg.ReadFrom(resp.Body)
+		return fmt.Errorf("%s%s: %s: %s", url, endpoint, resp.Status, strings.TrimSpace(msg.String()))
+	}
+	if reply == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(reply); err != nil {
+		return fmt.Errorf("%s%s: %w", url, endpoint, err)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package battlesnake
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/casen/snakegame/agent"
+	"github.com/casen/snakegame/snake"
+)
+
+// Server answers the Battlesnake engine with the moves of a trained agent.
+// Every move request is loaded into the server's game as a position and
+// played from there by the agent, one request at a time.
+type Server struct {
+	Info InfoResponse
+
+	game  *snake.Game
+	env   *snake.Env
+	ai    *agent.Agent
+	shape []int          // the observations the agent was made for
+	walls snake.WallMode // the walls the agent was trained with
+
+	mu  sync.Mutex // the game holds one position at a time
+	mux *http.ServeMux
+}
+
+// Creates a server for ai, which must have been made for an env of game, so
+// that its lookahead sees the positions the server loads. The rules of the
+// game's config, see Rules, are what the agent expects to happen next.
+func NewServer(game *snake.Game, ai *agent.Agent) *Server {
+	cfg := game.Config()
+	s := &Server{
+		Info:  InfoResponse{APIVersion: "1", Color: "#00ff00"},
+		game:  game,
+		env:   snake.NewEnv(game),
+		ai:    ai,
+		shape: cfg.Encoder.Shape(cfg.Rows, cfg.Cols),
+		walls: cfg.Walls,
+		mux:   http.NewServeMux(),
+	}
+	s.mux.HandleFunc("/", s.handleInfo)
+	s.mux.HandleFunc("/start", s.handleStart)
+	s.mux.HandleFunc("/move", s.handleMove)
+	s.mux.HandleFunc("/end", s.handleEnd)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Move picks the move of the snake the state was sent to
+func (s *Server) Move(state GameState) (string, error) {
+	pos, err := state.Position()
+	if err != nil {
+		return "", err
+	}
+	if shape := s.game.Config().Encoder.Shape(pos.Rows, pos.Cols); !slices.Equal(shape, s.shape) {
+		return "", fmt.Errorf("the agent sees observations shaped %v, a %dx%d board is shaped %v", s.shape, pos.Rows, pos.Cols, shape)
+	}
+	if pos.Walls != s.walls {
+		return "", fmt.Errorf("the agent was trained %s, the game is played %s", wallsName(s.walls), wallsName(pos.Walls))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.game.Load(pos); err != nil {
+		return "", err
+	}
+	return MoveName(s.env.Direction(s.ai.BestMove(s.env.Observe()))), nil
+}
+
+// How a board with the given walls is played, for errors
+func wallsName(walls snake.WallMode) string {
+	if walls == snake.WrapAround {
+		return "on boards that wrap around"
+	}
+	return "with solid walls"
+}
+
+func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, s.Info)
+}
+
+func (s *Server) handleStart(w http.ResponseWriter, r *http.Request) {
+	state, ok := readState(w, r)
+	if !ok {
+		return
+	}
+	log.Printf("Game %s started on a %dx%d board with %d snakes", state.Game.ID, state.Board.Width, state.Board.Height, len(state.Board.Snakes))
+}
+
+func (s *Server) handleMove(w http.ResponseWriter, r *http.Request) {
+	state, ok := readState(w, r)
+	if !ok {
+		return
+	}
+
+	move, err := s.Move(state)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, MoveResponse{Move: move})
+}
+
+func (s *Server) handleEnd(w http.ResponseWriter, r *http.Request) {
+	state, ok := readState(w, r)
+	if !ok {
+		return
+	}
+	log.Printf("Game %s over after %d turns", state.Game.ID, state.Turn)
+}
+
+// Reads the state posted with the request, answering with an error when there
+// isn't one
+func readState(w http.ResponseWriter, r *http.Request) (GameState, bool) {
+	var state GameState
+	if r.Method != http.MethodPost {
+		http.Error(w, "expected a POST", http.StatusMethodNotAllowed)
+		return state, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(&state); err != nil {
+		http.Error(w, fmt.Sprintf("reading game state: %v", err), http.StatusBadRequest)
+		return state, false
+	}
+	return state, true
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Writing response: %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package battlesnake
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/casen/snakegame/agent"
+	"github.com/casen/snakegame/snake"
+)
+
+// A server playing a fresh agent on 11x11 boards by the Battlesnake rules
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	cfg := Rules(snake.DefaultConfig())
+	cfg.Rows, cfg.Cols = 11, 11
+	game, err := snake.NewGame(cfg)
+	if err != nil {
+		t.Fatalf("NewGame() = %v", err)
+	}
+
+	agentCfg := agent.DefaultConfig()
+	agentCfg.Epsilon = 0
+	ai, err := agent.NewAgent(snake.NewEnv(game), agentCfg)
+	if err != nil {
+		t.Fatalf("NewAgent() = %v", err)
+	}
+
+	server := httptest.NewServer(NewServer(game, ai))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestServerInfo(t *testing.T) {
+	server := newTestServer(t)
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("GET / = %v", err)
+	}
+	defer resp.Body.Close()
+
+	var info InfoResponse
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil || info.APIVersion != "1" {
+		t.Errorf("GET / = %+v, %v; want API version 1", info, err)
+	}
+}
+
+func TestServerRejectsBadRequests(t *testing.T) {
+	server := newTestServer(t)
+
+	testCases := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+	}{
+		{"Not a POST", http.MethodGet, "", http.StatusMethodNotAllowed},
+		{"Not JSON", http.MethodPost, "up", http.StatusBadRequest},
+		{"Smaller board, the features don't mind", http.MethodPost, stateJSON, http.StatusOK},
+		{"Wrapped board, the agent knows walls", http.MethodPost, strings.Replace(stateJSON, `"standard"`, `"wrapped"`, 1), http.StatusBadRequest},
+		{"No body to the snake", http.MethodPost, `{"board": {"height": 11, "width": 11}, "you": {"id": "me"}}`, http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tc.method, server.URL+"/move", strings.NewReader(tc.body))
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("%s /move = %v", tc.method, err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tc.wantStatus {
+				t.Errorf("%s /move = %s; want %d", tc.method, resp.Status, tc.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRefereeAgainstServer(t *testing.T) {
+	server := newTestServer(t)
+
+	cfg := Rules(snake.DefaultConfig())
+	cfg.Rows, cfg.Cols = 11, 11
+	cfg.Seed = 3
+	cfg.Rivals = []snake.Start{cfg.Mirrored()}
+
+	ref, err := NewReferee(cfg, server.URL, server.URL)
+	if err != nil {
+		t.Fatalf("NewReferee() = %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		result, err := ref.Play()
+		if err != nil {
+			t.Fatalf("Play() = %v", err)
+		}
+		if result.Turns == 0 || result.Winner < -1 || result.Winner > 1 || len(result.Causes) != 2 {
+			t.Errorf("Play() = %+v; want a game of two played to the end", result)
+		}
+		for player, cause := range result.Causes {
+			if (cause == snake.CauseNone) != (player == result.Winner) {
+				t.Errorf("Play() = %+v; want only the winner alive", result)
+			}
+		}
+	}
+}
+
+func TestNewRefereeNeedsARivalPerServer(t *testing.T) {
+	if _, err := NewReferee(snake.DefaultConfig(), "http://a", "http://b"); err == nil {
+		t.Errorf("NewReferee() with two servers and no rivals = nil; want an error")
+	}
+}
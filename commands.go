@@ -0,0 +1,626 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/casen/snakegame/agent"
+	"github.com/casen/snakegame/battlesnake"
+	"github.com/casen/snakegame/snake"
+)
+
+const defaultEpisodes = 100
+
+// The kinds of food, by -foods name
+var foodKinds = map[string]snake.FoodKind{
+	"normal": snake.NormalFood,
+	"bonus":  snake.BonusFood,
+	"poison": snake.PoisonFood,
+	"speed":  snake.SpeedFood,
+}
+
+// What the agent can be made to see of the board, by -encoder name
+var encoders = map[string]snake.StateEncoder{
+	"features": snake.Features{},
+	"grid":     snake.Grid{},
+	"rays":     snake.Raycasts{},
+	"flood":    snake.FloodFill{},
+	"board":    snake.BoardTensor{},
+}
+
+// What moves are worth to the agent, by -rewards name
+var rewardSchemes = map[string]snake.RewardFunc{
+	"shaped":   snake.DefaultRewards,
+	"sparse":   snake.Sparse{Food: 100, Death: -100},
+	"survival": snake.SurvivalBonus{Rewards: snake.Sparse{Food: 100, Death: -100}, Bonus: 0.1},
+	"starve":   snake.StarvationPenalty{Rewards: snake.DefaultRewards, After: 100, Penalty: -2},
+}
+
+// Flags shared by every command that sets up a game
+type gameOptions struct {
+	seed     int64
+	rows     int
+	cols     int
+	encoder  string
+	relative bool
+	wrap     bool
+	level    string
+	foods    string
+	rewards  string
+	starve   snake.StarveRule
+	endLoops bool
+	maxMoves int
+
+	// Adds a rival snake on the far side of the board, see snake.Config.Mirrored
+	versus bool
+}
+
+// Adds the flags of a game to fs, defaulting to the rules in defaults
+func gameFlags(fs *flag.FlagSet, defaults snake.Config) *gameOptions {
+	o := &gameOptions{}
+	fs.Int64Var(&o.seed, "seed", 0, "seed for food placement and the agent, 0 picks one from the clock")
+	fs.IntVar(&o.rows, "rows", defaults.Rows, "number of rows on the board")
+	fs.IntVar(&o.cols, "cols", defaults.Cols, "number of columns on the board")
+	fs.StringVar(&o.encoder, "encoder", "features", "what the agent sees of the board: features, grid, rays, flood or board")
+	fs.StringVar(&o.level, "level", "", "play on a level: one of "+strings.Join(snake.Levels(), ", ")+", or a level file; sets the board size")
+	fs.StringVar(&o.foods, "foods", "normal", "comma separated kinds of food on the board: normal, bonus, poison and speed")
+	fs.BoolVar(&o.wrap, "wrap", defaults.Walls == snake.WrapAround, "the snake leaves by one edge of the board and comes back in by the opposite one")
+	fs.IntVar(&o.starve.Limit, "starve", defaults.Starvation.Limit, "end the game after this many moves without eating, 0 never")
+	fs.IntVar(&o.starve.PerCell, "starve-per-cell", defaults.Starvation.PerCell, "more moves allowed without eating for every cell of the snake")
+	fs.BoolVar(&o.endLoops, "end-loops", defaults.EndLoops, "end the game once it comes back round to a board it has been on since the snake last ate")
+	fs.IntVar(&o.maxMoves, "max-moves", defaults.MaxMoves, "end a game that runs this many moves, 0 never")
+	fs.StringVar(&o.rewards, "rewards", "shaped", "what moves are worth to the agent: shaped, sparse, survival or starve")
+	fs.BoolVar(&o.relative, "relative", defaults.RelativeActions, "the agent turns left, goes straight or turns right instead of picking a direction")
+	return o
+}
+
+// Switches the rules that stop an agent playing on its own from getting stuck
+// to those of snake.TrainingConfig, leaving alone any that fs was given
+func (o *gameOptions) trainingRules(fs *flag.FlagSet) {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	rules := snake.TrainingConfig()
+	if !set["starve"] {
+		o.starve.Limit = rules.Starvation.Limit
+	}
+	if !set["starve-per-cell"] {
+		o.starve.PerCell = rules.Starvation.PerCell
+	}
+	if !set["end-loops"] {
+		o.endLoops = rules.EndLoops
+	}
+	if !set["max-moves"] {
+		o.maxMoves = rules.MaxMoves
+	}
+}
+
+// A seed of 0 is swapped for one from the clock, which gets logged so the run
+// can be reproduced
+func (o *gameOptions) pickSeed() int64 {
+	if o.seed == 0 {
+		o.seed = time.Now().UnixNano()
+		log.Printf("Using seed %d", o.seed)
+	}
+	return o.seed
+}
+
+func (o *gameOptions) newGame() (*snake.Game, error) {
+	cfg, err := o.config()
+	if err != nil {
+		return nil, err
+	}
+	return snake.NewGame(cfg)
+}
+
+// The rules of the game the flags describe
+func (o *gameOptions) config() (snake.Config, error) {
+	cfg := snake.DefaultConfig()
+	cfg.Rows = o.rows
+	cfg.Cols = o.cols
+	cfg.Seed = o.pickSeed()
+	cfg.RelativeActions = o.relative
+	cfg.Starvation = o.starve
+	cfg.EndLoops = o.endLoops
+	cfg.MaxMoves = o.maxMoves
+	if o.wrap {
+		cfg.Walls = snake.WrapAround
+	}
+	cfg.Foods = nil
+	for _, name := range strings.Split(o.foods, ",") {
+		kind, ok := foodKinds[strings.TrimSpace(name)]
+		if !ok {
+			return cfg, fmt.Errorf("unknown food %q", name)
+		}
+		cfg.Foods = append(cfg.Foods, snake.FoodRules[kind])
+	}
+	if o.level != "" {
+		level, err := snake.LoadLevel(o.level)
+		if err != nil {
+			return cfg, err
+		}
+		level.Apply(&cfg)
+	}
+	if o.versus {
+		cfg.Rivals = []snake.Start{cfg.Mirrored()}
+	}
+
+	var ok bool
+	if cfg.Encoder, ok = encoders[o.encoder]; !ok {
+		return cfg, fmt.Errorf("unknown encoder %q", o.encoder)
+	}
+	if cfg.Rewards, ok = rewardSchemes[o.rewards]; !ok {
+		return cfg, fmt.Errorf("unknown reward scheme %q", o.rewards)
+	}
+
+	return cfg, nil
+}
+
+func (o *gameOptions) agentConfig() agent.Config {
+	cfg := agent.DefaultConfig()
+	cfg.Seed = o.pickSeed()
+	return cfg
+}
+
+// Flags for commands that train an agent
+type trainOptions struct {
+	episodes   int
+	targetSync int
+	tau        float64
+	double     bool
+	memory     int
+	batch      int
+	conv       bool
+
+	prioritized bool
+	alpha       float64
+	beta        float64
+}
+
+func trainFlags(fs *flag.FlagSet) *trainOptions {
+	o := &trainOptions{}
+	defaults := agent.DefaultConfig()
+	fs.IntVar(&o.episodes, "episodes", defaultEpisodes, "number of training episodes")
+	fs.IntVar(&o.targetSync, "target-sync", defaults.TargetSync, "copy the weights into the target network every this many training steps")
+	fs.Float64Var(&o.tau, "tau", float64(defaults.Tau), "blend the weights into the target network at this rate after every step instead, 0 to sync")
+	fs.BoolVar(&o.double, "double", defaults.DoubleDQN, "use double DQN for the bootstrapped values")
+	fs.BoolVar(&o.conv, "conv", defaults.Conv, "learn with a conv network, needs the grid or board encoder")
+	fs.IntVar(&o.batch, "batch", defaults.BatchSize, "memories learnt from per training step")
+	fs.IntVar(&o.memory, "memory", defaults.MemoryCapacity, "most memories kept for replay, each holds two observations of 4 bytes a value, 0 fits as many as 256 MB allows up to 100000")
+	fs.BoolVar(&o.prioritized, "prioritized", defaults.Prioritized, "replay memories by how surprising they were instead of uniformly")
+	fs.Float64Var(&o.alpha, "alpha", float64(defaults.PriorityAlpha), "how much priorities count in prioritized replay, 0 is uniform")
+	fs.Float64Var(&o.beta, "beta", float64(defaults.PriorityBetaStart), "starting importance sampling correction of prioritized replay, annealed to 1")
+	return o
+}
+
+func (o *trainOptions) apply(cfg *agent.Config) {
+	cfg.TargetSync = o.targetSync
+	cfg.Tau = float32(o.tau)
+	cfg.DoubleDQN = o.double
+	cfg.MemoryCapacity = o.memory
+	cfg.BatchSize = o.batch
+	cfg.Conv = o.conv
+	cfg.Prioritized = o.prioritized
+	cfg.PriorityAlpha = float32(o.alpha)
+	cfg.PriorityBetaStart = float32(o.beta)
+}
+
+// Flags of the commands that can draw games out to files, for machines
+// without a display
+type animationOptions struct {
+	gif    string
+	frames string
+	cell   int
+}
+
+func animationFlags(fs *flag.FlagSet, what string) *animationOptions {
+	o := &animationOptions{}
+	fs.StringVar(&o.gif, "gif", "", "draw "+what+" into this file as an animated GIF")
+	fs.StringVar(&o.frames, "frames", "", "draw "+what+" into this directory as a PNG file a frame")
+	fs.IntVar(&o.cell, "cell", 0, "pixels a cell is drawn wide, 0 draws the board as big as the window")
+	return o
+}
+
+func (o *animationOptions) wanted() bool {
+	return o.gif != "" || o.frames != ""
+}
+
+func (o *animationOptions) newAnimation() *snake.Animation {
+	return &snake.Animation{Cell: o.cell}
+}
+
+// Writes the animation out as whichever of a GIF and PNG frames were asked for
+func (o *animationOptions) write(a *snake.Animation) error {
+	if o.gif != "" {
+		f, err := os.Create(o.gif)
+		if err != nil {
+			return err
+		}
+		if err := a.WriteGIF(f); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		log.Printf("Drew %d frames into %s", a.Len(), o.gif)
+	}
+	if o.frames != "" {
+		if err := a.WritePNGs(o.frames); err != nil {
+			return err
+		}
+		log.Printf("Drew %d frames into %s", a.Len(), o.frames)
+	}
+	return nil
+}
+
+func runTrain(args []string) error {
+	fs := flag.NewFlagSet("train", flag.ExitOnError)
+	out := fs.String("out", "", "save the trained weights to this checkpoint")
+	opts := gameFlags(fs, snake.TrainingConfig())
+	train := trainFlags(fs)
+	fs.Parse(args)
+
+	if *out == "" {
+		return errors.New("-out is required")
+	}
+
+	ai, _, err := trainAgent(opts, train)
+	if err != nil {
+		return err
+	}
+
+	if err := ai.Save(*out); err != nil {
+		return err
+	}
+	log.Printf("Saved checkpoint %s", *out)
+
+	return nil
+}
+
+func runPlay(args []string) error {
+	fs := flag.NewFlagSet("play", flag.ExitOnError)
+	human := fs.Bool("human", true, "control the snake with the arrow keys, otherwise the agent plays")
+	model := fs.String("model", "", "checkpoint the agent plays with when -human=false or -versus agent")
+	versus := fs.String("versus", "", "play against a second snake: human, steered with WASD, or agent")
+	tui := fs.Bool("tui", false, "play in the terminal instead of a window, for machines without a display")
+	opts := gameFlags(fs, snake.DefaultConfig())
+	train := trainFlags(fs)
+	fs.Parse(args)
+
+	if *versus != "" {
+		return playVersus(opts, *versus, *model, *tui)
+	}
+
+	if *human {
+		game, err := opts.newGame()
+		if err != nil {
+			return err
+		}
+		if *tui {
+			return playTerminal(game, nil)
+		}
+		return playWindow(game, nil)
+	}
+
+	// The agent plays on its own, so it plays by the rules that stop it
+	// getting stuck, like in watch, unless the flags say otherwise
+	opts.trainingRules(fs)
+	return watch(opts, train, *model, *tui)
+}
+
+// Opens a window, or the terminal when tui is set, where a human plays against
+// a second snake, steered by another human or by the agent with the weights
+// in model
+func playVersus(opts *gameOptions, versus, model string, tui bool) error {
+	if versus != "human" && versus != "agent" {
+		return fmt.Errorf("unknown opponent %q, want human or agent", versus)
+	}
+	if versus == "agent" && model == "" {
+		return errors.New("-model is required to play against the agent")
+	}
+
+	opts.versus = true
+	game, err := opts.newGame()
+	if err != nil {
+		return err
+	}
+
+	var rival *agent.Agent
+	if versus == "agent" {
+		if rival, err = agent.Load(model, snake.NewPlayerEnv(game, 1), opts.agentConfig()); err != nil {
+			return err
+		}
+		log.Printf("Loaded checkpoint %s", model)
+	}
+
+	if tui {
+		return playTerminal(game, rival)
+	}
+	return playWindow(game, rival)
+}
+
+func runWatch(args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	model := fs.String("model", "", "checkpoint to play with, the agent is trained first when empty")
+	tui := fs.Bool("tui", false, "watch in the terminal instead of a window, for machines without a display")
+	opts := gameFlags(fs, snake.TrainingConfig())
+	train := trainFlags(fs)
+	fs.Parse(args)
+
+	return watch(opts, train, *model, *tui)
+}
+
+func runEval(args []string) error {
+	fs := flag.NewFlagSet("eval", flag.ExitOnError)
+	model := fs.String("model", "", "checkpoint to evaluate")
+	games := fs.Int("games", 1000, "number of games to play")
+	record := fs.String("record", "", "write every game down to this file, with the agent's Q-values, for replay")
+	anim := animationFlags(fs, "the best game")
+	opts := gameFlags(fs, snake.TrainingConfig())
+	fs.Parse(args)
+
+	if *model == "" {
+		return errors.New("-model is required")
+	}
+	if *games < 1 {
+		return fmt.Errorf("-games must be at least 1, got %d", *games)
+	}
+
+	game, err := opts.newGame()
+	if err != nil {
+		return err
+	}
+	env := snake.NewEnv(game)
+	ai, err := agent.Load(*model, env, opts.agentConfig())
+	if err != nil {
+		return err
+	}
+
+	var recorder *snake.Recorder
+	if *record != "" {
+		f, err := os.Create(*record)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		recorder = snake.NewRecorder(f)
+		game.Record(recorder)
+	}
+
+	// The game being played is drawn into film, and swapped into bestFilm
+	// when it beats the best game so far
+	var film, bestFilm *snake.Animation
+	if anim.wanted() {
+		film, bestFilm = anim.newAnimation(), anim.newAnimation()
+	}
+
+	var total, best, starved, looped, timeouts int
+	for i := 0; i < *games; i++ {
+		// The first game is the one the game starts out with
+		if i > 0 {
+			game.Reset()
+		}
+		if film != nil {
+			film.Reset()
+		}
+
+		for !game.GameOver() {
+			if film != nil {
+				film.Add(game)
+			}
+			state := env.Observe()
+			if recorder != nil {
+				q, err := ai.QValues(state)
+				if err != nil {
+					return err
+				}
+				recorder.QValues(q)
+			}
+			game.Step(env.Direction(ai.BestMove(state)))
+		}
+		switch game.Cause() {
+		case snake.CauseStarved:
+			starved++
+		case snake.CauseLooped:
+			looped++
+		case snake.CauseTimedOut:
+			timeouts++
+		}
+		if film != nil {
+			film.Add(game)
+			if i == 0 || game.Score() > best {
+				film, bestFilm = bestFilm, film
+			}
+		}
+
+		total += game.Score()
+		if game.Score() > best {
+			best = game.Score()
+		}
+	}
+
+	fmt.Printf("games:     %d\n", *games)
+	fmt.Printf("mean:      %.2f\n", float64(total)/float64(*games))
+	fmt.Printf("best:      %d\n", best)
+	fmt.Printf("starved:   %d\n", starved)
+	fmt.Printf("looped:    %d\n", looped)
+	fmt.Printf("timed out: %d\n", timeouts)
+
+	if recorder != nil {
+		if err := recorder.Flush(); err != nil {
+			return err
+		}
+		log.Printf("Recorded %d games to %s", *games, *record)
+	}
+	if bestFilm != nil {
+		return anim.write(bestFilm)
+	}
+
+	return nil
+}
+
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8000", "address to listen on")
+	model := fs.String("model", "", "checkpoint the agent plays with")
+	opts := gameFlags(fs, snake.DefaultConfig())
+	fs.Parse(args)
+
+	if *model == "" {
+		return errors.New("-model is required")
+	}
+
+	cfg, err := opts.config()
+	if err != nil {
+		return err
+	}
+	game, err := snake.NewGame(battlesnake.Rules(cfg))
+	if err != nil {
+		return err
+	}
+	ai, err := agent.Load(*model, snake.NewEnv(game), opts.agentConfig())
+	if err != nil {
+		return err
+	}
+	log.Printf("Loaded checkpoint %s", *model)
+
+	log.Printf("Serving Battlesnake moves on %s", *addr)
+	return http.ListenAndServe(*addr, battlesnake.NewServer(game, ai))
+}
+
+func runReferee(args []string) error {
+	fs := flag.NewFlagSet("referee", flag.ExitOnError)
+	games := fs.Int("games", 1, "number of games to play")
+	opts := gameFlags(fs, snake.DefaultConfig())
+	fs.Parse(args)
+
+	urls := fs.Args()
+	if len(urls) < 1 || len(urls) > 2 {
+		return errors.New("expected the URL of one or two Battlesnake servers")
+	}
+
+	cfg, err := opts.config()
+	if err != nil {
+		return err
+	}
+	cfg = battlesnake.Rules(cfg)
+	if len(urls) == 2 {
+		cfg.Rivals = []snake.Start{cfg.Mirrored()}
+	}
+	ref, err := battlesnake.NewReferee(cfg, urls...)
+	if err != nil {
+		return err
+	}
+
+	wins := make([]int, len(urls))
+	for i := 0; i < *games; i++ {
+		result, err := ref.Play()
+		if err != nil {
+			return err
+		}
+		if result.Winner >= 0 {
+			wins[result.Winner]++
+		}
+		fmt.Printf("game %d: %d turns, winner %d, causes %v\n", i+1, result.Turns, result.Winner+1, result.Causes)
+	}
+	for i, url := range urls {
+		fmt.Printf("%s won %d of %d\n", url, wins[i], *games)
+	}
+
+	return nil
+}
+
+func runReplay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	episode := fs.Int("episode", 1, "which of the recorded games to replay, counting from 1")
+	anim := animationFlags(fs, "the game, instead of opening a window,")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return errors.New("expected exactly one replay file")
+	}
+
+	replayer, err := openReplay(fs.Arg(0), *episode)
+	if err != nil {
+		return err
+	}
+	if anim.wanted() {
+		film := replayer.Animate(anim.cell)
+		return anim.write(film)
+	}
+	return replayWindow(replayer)
+}
+
+// Reads the recording at path and sets up a replay of one of its episodes
+func openReplay(path string, episode int) (*snake.Replayer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	episodes, err := snake.ReadRecording(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if episode < 1 || episode > len(episodes) {
+		return nil, fmt.Errorf("%s has %d games, there's no game %d", path, len(episodes), episode)
+	}
+	return snake.NewReplayer(episodes[episode-1])
+}
+
+// Trains a fresh agent, leaving the game it was trained on reset and ready to play
+func trainAgent(opts *gameOptions, train *trainOptions) (*agent.Agent, *snake.Game, error) {
+	game, err := opts.newGame()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cfg := opts.agentConfig()
+	train.apply(&cfg)
+	ai, err := agent.NewAgent(snake.NewEnv(game), cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := ai.Train(train.episodes); err != nil {
+		return nil, nil, err
+	}
+	game.Reset()
+
+	log.Printf("Training complete")
+
+	return ai, game, nil
+}
+
+// Opens a window, or the terminal when tui is set, where the agent plays with
+// the weights in model, or with freshly trained weights when model is empty
+func watch(opts *gameOptions, train *trainOptions, model string, tui bool) error {
+	var ai *agent.Agent
+	var game *snake.Game
+	var err error
+
+	if model != "" {
+		if game, err = opts.newGame(); err != nil {
+			return err
+		}
+		if ai, err = agent.Load(model, snake.NewEnv(game), opts.agentConfig()); err != nil {
+			return err
+		}
+		log.Printf("Loaded checkpoint %s", model)
+	} else if ai, game, err = trainAgent(opts, train); err != nil {
+		return err
+	}
+
+	if tui {
+		return watchTerminal(game, ai)
+	}
+	return watchWindow(game, ai)
+}
@@ -1,7 +1,10 @@
+//go:build !headless
+
 package main
 
 import (
 	"log"
+	"time"
 
 	"github.com/casen/snakegame/agent"
 	"github.com/casen/snakegame/model"
@@ -9,87 +12,105 @@ import (
 	"github.com/hajimehoshi/ebiten/v2"
 )
 
-const (
-	ScreenWidth  = 600
-	ScreenHeight = 600
-)
-
 type GamePlayer struct {
-	visited   []model.Point
-	highScore int
-	game      *snake.Game
-	agent     *agent.Agent
-	input     *snake.Input
-	ai        bool
+	game *snake.Game
+	ai   *autoplay // the agent playing on its own, nil when humans play
+
+	// Who steers each snake when humans play, by player, and the keys they
+	// steer with, nil for a snake the agent steers
+	players []*controller
+	inputs  []*snake.Input
+
+	// Game time since the snakes last stepped
+	elapsed time.Duration
 }
 
+// Creates a player for the game. The agent is only needed when it's the AI playing
 func NewGamePlayer(game *snake.Game, agent *agent.Agent, ai bool) *GamePlayer {
-	if game == nil || agent == nil {
+	if game == nil || (ai && agent == nil) {
+		return nil
+	}
+
+	if ai {
+		return &GamePlayer{game: game, ai: newAutoplay(game, agent)}
+	}
+	return &GamePlayer{
+		game:    game,
+		players: newControllers(game, nil),
+		inputs:  []*snake.Input{snake.NewInput()},
+	}
+}
+
+// Creates a player for a game of two snakes, the first steered with the
+// arrow keys. The second is steered by rival, or with WASD by a second human
+// on the same keyboard when rival is nil.
+func NewVersusPlayer(game *snake.Game, rival *agent.Agent) *GamePlayer {
+	if game == nil || game.Players() != 2 {
 		return nil
 	}
 
+	inputs := []*snake.Input{snake.NewInputWithKeys(snake.ArrowKeys), nil}
+	if rival == nil {
+		inputs[1] = snake.NewInputWithKeys(snake.WASDKeys)
+	}
+
 	return &GamePlayer{
-		visited:   make([]model.Point, 0),
-		highScore: 0,
-		game:      game,
-		agent:     agent,
-		input:     snake.NewInput(),
-		ai:        ai,
+		game:    game,
+		players: newControllers(game, rival),
+		inputs:  inputs,
 	}
 }
 
 func (gp *GamePlayer) HumanMove() error {
-	_, userAction, ok := gp.input.Action()
-	currDir := gp.game.CurrentDirection()
-	finalAction := userAction
+	for i, input := range gp.inputs {
+		if input == nil {
+			continue
+		}
+		if _, userAction, ok := input.Action(); ok {
+			gp.players[i].pending = userAction
+		}
+	}
 
-	if !ok {
-		finalAction = currDir
+	if gp.game.GameOver() || !gp.tick() {
+		return nil
 	}
 
-	return gp.game.Update(finalAction)
+	actions := make([]model.Vector, len(gp.players))
+	for i, c := range gp.players {
+		actions[i] = c.action()
+	}
+	gp.game.StepAll(actions)
+
+	return nil
 }
 
 func (gp *GamePlayer) AiMove() error {
-
-	if len(gp.visited) == 128 {
-		startIdx, period, hasCycle := DetectCycles(gp.visited)
-
-		if hasCycle {
-			log.Printf("Found cycle at startIdx: %d, period: %d", startIdx, period)
-			log.Printf("Game over. Score %v, High score %v. Resetting game", gp.game.Score(), gp.highScore)
-			if gp.game.Score() > gp.highScore {
-				gp.highScore = gp.game.Score()
-			}
-			gp.game.Reset()
-		} else {
-			log.Printf("No cycle found")
-			log.Print(gp.visited)
-		}
-		clear(gp.visited)
+	if !gp.tick() {
+		return nil
 	}
 
-	if gp.game.GameOver() {
-		if gp.game.Score() > gp.highScore {
-			gp.highScore = gp.game.Score()
-		}
-		log.Printf("Game over. Score %v, High score %v. Resetting game", gp.game.Score(), gp.highScore)
-		gp.game.Reset()
-		clear(gp.visited)
+	if msg := gp.ai.step(); msg != "" {
+		log.Print(msg)
 	}
 
-	agentAction := gp.agent.BestMove()
+	return nil
+}
 
-	// If we're not moving, we're not going to add the current location to the visited array
-	if len(gp.visited) < 1 || gp.visited[len(gp.visited)-1] != gp.game.CurrentLocation() {
-		gp.visited = append(gp.visited, gp.game.CurrentLocation())
+// Reports whether enough game time has passed for the snake to take its next
+// step. Time is counted in ebiten ticks rather than read off the clock, so the
+// pace doesn't drift when frames are slow.
+func (gp *GamePlayer) tick() bool {
+	gp.elapsed += time.Second / time.Duration(ebiten.TPS())
+	if gp.elapsed < gp.game.Interval() {
+		return false
 	}
 
-	return gp.game.Update(agentAction)
+	gp.elapsed = 0
+	return true
 }
 
 func (gp *GamePlayer) Update() error {
-	if gp.ai {
+	if gp.ai != nil {
 		return gp.AiMove()
 	} else {
 		return gp.HumanMove()
@@ -101,5 +122,5 @@ func (gp *GamePlayer) Draw(screen *ebiten.Image) {
 }
 
 func (gp *GamePlayer) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
-	return ScreenWidth, ScreenHeight
+	return gp.game.Layout(outsideWidth, outsideHeight)
 }
@@ -1,29 +1,57 @@
 package main
 
 import (
-	"log"
-
-	"github.com/casen/snakegame/agent"
-	"github.com/casen/snakegame/snake"
-	"github.com/hajimehoshi/ebiten/v2"
+	"fmt"
+	"os"
 )
 
-func main() {
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
 
-	// Game defaults to user input
-	game := snake.NewGame()
-	ai := agent.NewAgent(game)
+var commands = []command{
+	{"train", "train the agent and save its weights", runTrain},
+	{"play", "play the game yourself with the arrow keys", runPlay},
+	{"watch", "watch a trained agent play", runWatch},
+	{"eval", "measure a trained agent over many headless games", runEval},
+	{"replay", "replay a recorded game", runReplay},
+	{"serve", "serve a trained agent as a Battlesnake", runServe},
+	{"referee", "play games between Battlesnake servers locally", runReferee},
+}
 
-	ai.Train()
-	game.Reset()
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
 
-	log.Printf("Training complete")
+	name := os.Args[1]
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+
+		if err := cmd.run(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "snakegame %s: %v\n", name, err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	player := NewGamePlayer(game, ai, true)
+	fmt.Fprintf(os.Stderr, "snakegame: unknown command %q\n", name)
+	usage()
+	os.Exit(2)
+}
 
-	ebiten.SetWindowSize(snake.ScreenWidth, snake.ScreenHeight)
-	ebiten.SetWindowTitle("Snake")
-	if err := ebiten.RunGame(player); err != nil {
-		log.Fatal(err)
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: snakegame <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", cmd.name, cmd.summary)
 	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "run 'snakegame <command> -h' for the flags of a command")
 }
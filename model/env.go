@@ -0,0 +1,67 @@
+package model
+
+// Observation is what an environment lets the agent see of its state
+type Observation []float32
+
+// Action is one of the discrete moves an environment accepts, numbered from 0
+type Action int
+
+// Discrete is a space of N actions, 0 through N-1
+type Discrete struct {
+	N int
+}
+
+// Box is a space of observations with the given shape, each value between Low and High
+type Box struct {
+	Shape []int
+	Low   float32
+	High  float32
+}
+
+// Size is the number of values in an observation from the space
+func (b Box) Size() int {
+	size := 1
+	for _, dim := range b.Shape {
+		size *= dim
+	}
+	return size
+}
+
+// Info carries what an environment knows about a step beyond the reward
+type Info struct {
+	Score int
+
+	// What happened on the step, so agents needn't guess it from the reward
+	Scored bool
+	Died   bool
+}
+
+// Env is a game an agent learns to play, one step at a time
+type Env interface {
+	// Reset starts a new episode and returns its first observation
+	Reset() Observation
+
+	// Step takes the action and reports what it led to. Once done is true the
+	// episode is over and the env needs a Reset.
+	Step(action Action) (obs Observation, reward float32, done bool, info Info)
+
+	ActionSpace() Discrete
+	ObservationSpace() Box
+
+	// LegalActions are the actions worth telling apart in the current state.
+	// The rest of the action space does the same as one of them.
+	LegalActions() []Action
+}
+
+// Variant is implemented by environments that come in variants an agent
+// trained on one can't play another of, such as what it sees of the game or
+// what its actions mean. Checkpoints record the variant they were trained on.
+type Variant interface {
+	Variant() map[string]string
+}
+
+// Lookahead is implemented by environments that can try out an action without
+// committing to it
+type Lookahead interface {
+	Peek(action Action) (obs Observation, reward float32, done bool, info Info)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/casen/snakegame/agent"
+	"github.com/casen/snakegame/model"
+	"github.com/casen/snakegame/snake"
+)
+
+// What the frontends share of playing a game: who steers each snake, and the
+// agent playing on its own
+
+// controller steers one snake, by the keys a frontend reads or by an agent
+type controller struct {
+	agent *agent.Agent
+	env   *snake.Env // what the agent sees of the game, as its snake
+
+	// The direction the player asked for since the snake last stepped
+	pending model.Vector
+}
+
+// The direction the snake goes in this tick
+func (c *controller) action() model.Vector {
+	if c.agent != nil {
+		return c.env.Direction(c.agent.BestMove(c.env.Observe()))
+	}
+	action := c.pending
+	c.pending = model.Vector{}
+	return action
+}
+
+// The controllers of a game played by humans, by player. The second snake of
+// a game of two is steered by rival, or by a second human when rival is nil.
+func newControllers(game *snake.Game, rival *agent.Agent) []*controller {
+	players := []*controller{{}}
+	if game.Players() > 1 {
+		second := &controller{}
+		if rival != nil {
+			second = &controller{agent: rival, env: snake.NewPlayerEnv(game, 1)}
+		}
+		players = append(players, second)
+	}
+	return players
+}
+
+// autoplay has the agent play game after game on its own, starting over
+// whenever a game ends. The rules of the game decide when an agent going
+// round in circles is stopped.
+type autoplay struct {
+	highScore int
+	game      *snake.Game
+	env       *snake.Env // what the agent sees of the game
+	agent     *agent.Agent
+}
+
+func newAutoplay(game *snake.Game, ai *agent.Agent) *autoplay {
+	return &autoplay{
+		game:  game,
+		env:   snake.NewEnv(game),
+		agent: ai,
+	}
+}
+
+// Plays a tick. When the game starts over instead, it says why.
+func (a *autoplay) step() string {
+	var msg string
+	if a.game.GameOver() {
+		if a.game.Score() > a.highScore {
+			a.highScore = a.game.Score()
+		}
+		msg = fmt.Sprintf("Game over (%v). Score %v, High score %v. Resetting game", a.game.Cause(), a.game.Score(), a.highScore)
+		a.reset()
+	}
+
+	agentAction := a.agent.BestMove(a.env.Observe())
+	a.game.Step(a.env.Direction(agentAction))
+	return msg
+}
+
+func (a *autoplay) reset() {
+	a.game.Reset()
+}
@@ -0,0 +1,86 @@
+//go:build !headless
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/casen/snakegame/snake"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// ReplayViewer shows a recorded episode. Space plays and pauses it, the left
+// and right arrows step back and forward a tick, page up and down seek ten
+// ticks at a time, and home and end go to the start and the end.
+type ReplayViewer struct {
+	replayer *snake.Replayer
+	playing  bool
+
+	// Game time since the replay last stepped while playing
+	elapsed time.Duration
+}
+
+func NewReplayViewer(replayer *snake.Replayer) *ReplayViewer {
+	return &ReplayViewer{replayer: replayer, playing: true}
+}
+
+// How far page up and down seek
+const seekTicks = 10
+
+func (v *ReplayViewer) Update() error {
+	r := v.replayer
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeySpace):
+		v.playing = !v.playing
+	case inpututil.IsKeyJustPressed(ebiten.KeyArrowRight):
+		v.playing = false
+		r.Forward()
+	case inpututil.IsKeyJustPressed(ebiten.KeyArrowLeft):
+		v.playing = false
+		r.Back()
+	case inpututil.IsKeyJustPressed(ebiten.KeyPageDown):
+		r.Seek(r.Tick() + seekTicks)
+	case inpututil.IsKeyJustPressed(ebiten.KeyPageUp):
+		r.Seek(r.Tick() - seekTicks)
+	case inpututil.IsKeyJustPressed(ebiten.KeyHome):
+		r.Seek(0)
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnd):
+		r.Seek(r.Len())
+	}
+
+	if !v.playing {
+		return nil
+	}
+	// Paced like the game was, counting ebiten ticks rather than the clock
+	v.elapsed += time.Second / time.Duration(ebiten.TPS())
+	if v.elapsed >= r.Game().Interval() {
+		v.elapsed = 0
+		if !r.Forward() {
+			v.playing = false
+		}
+	}
+	return nil
+}
+
+func (v *ReplayViewer) Draw(screen *ebiten.Image) {
+	r := v.replayer
+	r.Game().Draw(screen)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Tick %d/%d", r.Tick(), r.Len())
+	if !v.playing {
+		sb.WriteString(" (paused)")
+	}
+	if next, ok := r.Next(); ok && next.QValues != nil {
+		fmt.Fprintf(&sb, "\nQ: %.2f", next.QValues)
+	}
+	ebitenutil.DebugPrintAt(screen, sb.String(), 0, 16)
+}
+
+func (v *ReplayViewer) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	return v.replayer.Game().Layout(outsideWidth, outsideHeight)
+}
@@ -0,0 +1,112 @@
+package snake
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Animation collects the frames of a game as it's played, or of a replay as
+// it's stepped through, and writes them out as an animated GIF or as a PNG
+// file a frame. Frames are kept in a palette of the colors a board is drawn
+// in, a byte a pixel, so even a long game fits in memory.
+type Animation struct {
+	Cell int // how wide a cell is drawn, see Game.Render
+
+	frames []*image.Paletted
+	delays []int // how long each frame shows, in 100ths of a second
+}
+
+// How long the last frame of a GIF is held before it starts over, in 100ths
+// of a second
+const gifEndDelay = 100
+
+// The colors of a board as they come out drawn over the background, with the
+// background and the text
+var palette = func() color.Palette {
+	bg := backgroundColor
+	bg.A = 255
+	p := color.Palette{bg, textColor, portalColor, obstacleColor}
+	for _, c := range snakeColors {
+		p = append(p, c)
+	}
+	for _, c := range foodColors {
+		p = append(p, over(c, bg))
+	}
+	return p
+}()
+
+// The color c comes out as drawn over the opaque color bg
+func over(c, bg color.RGBA) color.RGBA {
+	a := 255 - uint32(c.A)
+	blend := func(src, dst uint8) uint8 {
+		return uint8(min(255, uint32(src)+uint32(dst)*a/255))
+	}
+	return color.RGBA{blend(c.R, bg.R), blend(c.G, bg.G), blend(c.B, bg.B), 255}
+}
+
+// Add adds the game as it is now as a frame, shown for as long as the game
+// waits before its next step
+func (a *Animation) Add(g *Game) {
+	img := g.Render(a.Cell)
+	frame := image.NewPaletted(img.Bounds(), palette)
+	draw.Draw(frame, frame.Rect, img, image.Point{}, draw.Src)
+
+	a.frames = append(a.frames, frame)
+	// Browsers slow down anything shorter than 2
+	a.delays = append(a.delays, max(2, int(g.Interval()/(10*time.Millisecond))))
+}
+
+// Len is how many frames the animation has
+func (a *Animation) Len() int {
+	return len(a.frames)
+}
+
+// Reset drops the frames, to start on another game
+func (a *Animation) Reset() {
+	a.frames, a.delays = nil, nil
+}
+
+// WriteGIF writes the frames as a GIF that plays on a loop
+func (a *Animation) WriteGIF(w io.Writer) error {
+	if len(a.frames) == 0 {
+		return errors.New("animation has no frames")
+	}
+	delays := append([]int(nil), a.delays...)
+	delays[len(delays)-1] += gifEndDelay
+	return gif.EncodeAll(w, &gif.GIF{Image: a.frames, Delay: delays})
+}
+
+// WritePNGs writes each frame to a file of its own in dir, frame-0000.png
+// onwards, creating dir if need be
+func (a *Animation) WritePNGs(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for i, frame := range a.frames {
+		if err := writePNG(filepath.Join(dir, fmt.Sprintf("frame-%04d.png", i)), frame); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
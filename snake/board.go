@@ -1,11 +1,12 @@
 package snake
 
 import (
-	"fmt"
 	"log"
 	"math"
 	"math/rand"
-	"time"
+	"os"
+	"slices"
+	"strings"
 
 	"github.com/casen/snakegame/model"
 )
@@ -13,109 +14,475 @@ import (
 type Board struct {
 	rows     int
 	cols     int
-	food     model.Point
-	snake    *Snake
-	points   int
+	foods    []Food
+	rules    []FoodRule
 	gameOver bool
-	timer    time.Time
+	rng      *rand.Rand
+	growth   int
+	walls    WallMode
+	rewards  RewardFunc
+
+	// Cells inside the board the snake can't go through, shared by clones
+	obstacles map[model.Point]bool
+
+	// Fixed spots for the normal food, and which of them is next
+	spots    []model.Point
+	nextSpot int
+
+	// Every snake on the board, the dead ones too, and the one the board is
+	// seen from, see As
+	snakes []*Snake
+	me     int
+	snake  *Snake
+
+	boost    int // ticks left of speed food
+	starve   StarveRule
+	moves    int // ticks since the game started
+	maxMoves int
+	loops    *CycleDetector // ends the game when it goes round in circles, nil to let it
+
+	// Kept up to date move by move, once someone has asked for the hash
+	zobrist *Zobrist
+	hash    uint64
 }
 
-// Creates a new board with random food position and snake starting in top-left corner for normal gameplay
-func NewGameBoard(rows int, cols int) *Board {
+// Creates a new board for normal gameplay, with the snakes where the config
+// starts them and the food spawned by the rules of the config. All food is
+// placed with rng, so boards built from the same seed play out the same way.
+func NewGameBoard(cfg Config, rng *rand.Rand) *Board {
+	snakes := []*Snake{newStartingSnake(Start{cfg.Snake, cfg.Direction})}
+	for _, rival := range cfg.Rivals {
+		snakes = append(snakes, newStartingSnake(rival))
+	}
+	board := newRulesBoard(cfg, rng, snakes)
+	for _, r := range board.rules {
+		for i := 0; i < r.Spawn.Min; i++ {
+			board.addFood(r)
+		}
+	}
+	if cfg.EndLoops {
+		board.loops = NewCycleDetector()
+		board.loops.Observe(board.Hash())
+	}
 
-	// start in top-left corner
-	snake := NewSnake([]model.Point{{X: 0, Y: 0}, {X: 0, Y: 1}, {X: 0, Y: 2}, {X: 0, Y: 3}}, model.Vector{X: 0, Y: 1})
-	food := PlaceFood(rows, cols, snake)
+	return board
+}
 
-	return NewBoard(rows, cols, snake, food)
+// Creates a board with the given snakes and no food yet, playing by the rules
+// of the config
+func newRulesBoard(cfg Config, rng *rand.Rand, snakes []*Snake) *Board {
+	board := NewBoard(cfg.Rows, cfg.Cols, snakes[0], model.Point{})
+	board.snakes = snakes
+	board.foods = nil
+	board.rules = cfg.Foods
+	board.rng = rng
+	board.growth = cfg.Growth
+	board.walls = cfg.Walls
+	board.rewards = cfg.Rewards
+	board.starve = cfg.Starvation
+	board.maxMoves = cfg.MaxMoves
+	board.spots = cfg.FoodSpots
+	if len(cfg.Obstacles) > 0 {
+		board.obstacles = make(map[model.Point]bool, len(cfg.Obstacles))
+		for _, p := range cfg.Obstacles {
+			board.obstacles[p] = true
+		}
+	}
+	return board
 }
 
+func newStartingSnake(start Start) *Snake {
+	body := make([]model.Point, len(start.Snake))
+	copy(body, start.Snake)
+	return NewSnake(body, start.Direction)
+}
+
+// Creates a board with the snake and a normal food exactly where they're
+// given, playing by the default rules. Food placed later on comes from a fixed
+// seed, so these boards are reproducible too.
 func NewBoard(rows int, cols int, snake *Snake, food model.Point) *Board {
 
 	board := &Board{
 		rows:     rows,
 		cols:     cols,
-		timer:    time.Now(),
 		gameOver: false,
+		snakes:   []*Snake{snake},
 		snake:    snake,
-		food:     food,
+		foods:    []Food{{Kind: NormalFood, At: food}},
+		rules:    []FoodRule{FoodRules[NormalFood]},
+		rng:      rand.New(rand.NewSource(0)),
+		growth:   1,
+		rewards:  DefaultRewards,
 	}
 
 	return board
 }
 
-func PlaceFood(rows int, cols int, snake *Snake) model.Point {
-	var x, y int
-	var point model.Point
-
-	for {
-		x = rand.Intn(cols)
-		y = rand.Intn(rows)
-		point = model.Point{X: x, Y: y}
+// PlaceFood picks where the next food of the given kind goes: for normal food
+// the next of the fixed spots that's free, or failing that a random cell clear
+// of the snakes, the obstacles and the other foods. It reports false when no
+// cell is clear.
+func (b *Board) PlaceFood(kind FoodKind) (model.Point, bool) {
+	if kind == NormalFood {
+		for range b.spots {
+			point := b.spots[b.nextSpot]
+			b.nextSpot = (b.nextSpot + 1) % len(b.spots)
+			if b.free(point) {
+				return point, true
+			}
+		}
+	}
 
-		// make sure we don't put a food on a snake
-		if !snake.HitsSnake(point) {
-			break
+	var free []model.Point
+	for x := 0; x < b.rows; x++ {
+		for y := 0; y < b.cols; y++ {
+			if p := (model.Point{X: x, Y: y}); b.free(p) {
+				free = append(free, p)
+			}
 		}
 	}
+	if len(free) == 0 {
+		return model.Point{}, false
+	}
+	return free[b.rng.Intn(len(free))], true
+}
 
-	return point
+// Whether p is clear of the snakes, the obstacles and the foods
+func (b *Board) free(p model.Point) bool {
+	_, taken := b.foodAt(p)
+	return !taken && !b.hitsSnake(p) && !b.obstacles[p]
 }
 
-func (b *Board) Update(action model.Vector) error {
-	if b.gameOver {
-		return nil
+// Whether any snake still alive is at p
+func (b *Board) hitsSnake(p model.Point) bool {
+	for _, s := range b.snakes {
+		if s.alive() && s.HitsSnake(p) {
+			return true
+		}
 	}
+	return false
+}
 
-	// snake goes faster when there are more points
-	interval := time.Millisecond * 150
-	if b.points > 10 {
-		interval = time.Millisecond * 125
-	} else if b.points > 20 {
-		interval = time.Millisecond * 100
+// The snakes still alive other than the one the board is seen from
+func (b *Board) others() []*Snake {
+	var others []*Snake
+	for i, s := range b.snakes {
+		if i != b.me && s.alive() {
+			others = append(others, s)
+		}
 	}
+	return others
+}
 
-	b.snake.ChangeDirection(action)
+// As is the board seen from the i-th snake: the snake that Head, Food,
+// CurrentState, the encoders and the rewards are about. The view shares the
+// board, so it's for looking at; Clone it to try moves out.
+func (b *Board) As(i int) *Board {
+	view := *b
+	view.me, view.snake = i, b.snakes[i]
+	return &view
+}
 
-	if time.Since(b.timer) >= interval {
-		if err := b.MoveSnake(); err != nil {
-			return err
-		}
+// Snakes is how many snakes the board started with
+func (b *Board) Snakes() int {
+	return len(b.snakes)
+}
+
+// Obstacle reports whether there's an obstacle at p
+func (b *Board) Obstacle(p model.Point) bool {
+	return b.obstacles[p]
+}
 
-		b.timer = time.Now()
+// The head of the snake
+func (b *Board) Head() model.Point {
+	return b.snake.Head()
+}
+
+// Where the food the snake should go for is: the nearest one worth eating, or
+// the head itself when there's none
+func (b *Board) Food() model.Point {
+	head := b.snake.Head()
+	target, best := head, math.Inf(1)
+	for _, f := range b.foods {
+		if d := b.distance(head, f.At); f.edible() && d < best {
+			target, best = f.At, d
+		}
 	}
+	return target
+}
+
+// Foods lists the food on the board
+func (b *Board) Foods() []Food {
+	return append([]Food(nil), b.foods...)
+}
+
+// Whether the snake is sped up by a speed food
+func (b *Board) Boosted() bool {
+	return b.boost > 0
+}
+
+// How long the snake is
+func (b *Board) Length() int {
+	return len(b.snake.body)
+}
+
+// How many moves the snake has made since it last ate
+func (b *Board) Hunger() int {
+	return b.snake.hunger
+}
+
+// What the snake has scored
+func (b *Board) Score() int {
+	return b.snake.points
+}
 
-	return nil
+// Why the snake died, CauseNone while it's alive
+func (b *Board) Cause() Cause {
+	return b.snake.cause
 }
 
 func (b *Board) GameOver() bool {
 	return b.gameOver
 }
 
-func (b *Board) MoveSnake() error {
-	// remove tail first, add 1 in front
-	b.snake.Move()
-	snakeHead := b.snake.Head()
+// Advances the snake one cell in its current direction, the other snakes going
+// on the way they're heading. Reports whether it ate.
+func (b *Board) MoveSnake() (ateFood bool) {
+	ate, _ := b.moveSnakes()
+	return ate[b.me]
+}
 
-	movedOutOfBounds := b.OutOfBounds(snakeHead.X, snakeHead.Y)
-	headHitBody := b.snake.HeadHitsBody()
+// Advances every live snake one cell in its current direction, all at once.
+// A snake dies running into a wall, an obstacle, itself or another snake, and
+// when two meet head on, the shorter dies, or both when they're as long as
+// each other. The survivors eat whatever they landed on, then any that went
+// too long without eating starve. The food on the board is topped up every
+// tick.
+//
+// One snake alone plays until it dies. With more, the game is over once one
+// or none are left.
+func (b *Board) moveSnakes() (ate []bool, eaten []*Food) {
+	ate = make([]bool, len(b.snakes))
+	eaten = make([]*Food, len(b.snakes))
+
+	var alive []int
+	for i, s := range b.snakes {
+		if s.alive() {
+			alive = append(alive, i)
+			// remove tail first, add 1 in front
+			s.Move()
+			s.body[len(s.body)-1] = b.wrap(s.Head())
+		}
+	}
 
-	if movedOutOfBounds || headHitBody {
-		//log.Printf("Game over condition: movedOutOfBounds=%v headHitBody=%v", movedOutOfBounds, headHitBody)
-		//log.Printf("Snake %v", b.snake.body)
-		//log.Printf("Dir %v", b.snake.direction)
+	// Everyone has moved before anyone is checked, so the snakes that die
+	// this tick still get in each other's way
+	causes := make([]Cause, len(b.snakes))
+	for _, i := range alive {
+		causes[i] = b.collision(i, alive)
+	}
+	for _, i := range alive {
+		b.snakes[i].cause = causes[i]
+	}
+	if b.over() {
 		b.gameOver = true
-		return nil
+		return ate, eaten
 	}
 
-	if b.snake.HeadHits(b.food) {
-		// the snake grows on the next move
-		b.snake.justAte = true
-		b.food = PlaceFood(b.rows, b.cols, b.snake)
-		b.points++
+	if b.boost > 0 {
+		b.boost--
 	}
+	for _, i := range alive {
+		s := b.snakes[i]
+		if !s.alive() {
+			continue
+		}
+		s.hunger++
+		if j, ok := b.foodAt(s.Head()); ok {
+			food := b.foods[j]
+			eaten[i] = &food
+			ate[i] = b.eat(s, j)
+		}
+	}
+	if b.over() {
+		b.gameOver = true
+		return ate, eaten
+	}
+	b.spawnFood()
 
-	return nil
+	for _, i := range alive {
+		s := b.snakes[i]
+		if s.alive() && !ate[i] && b.starve.Limit > 0 && s.hunger > b.starve.limit(len(s.body)) {
+			s.cause = CauseStarved
+		}
+	}
+	b.gameOver = b.over()
+
+	return ate, eaten
+}
+
+// What the i-th snake ran into, of the snakes alive before the move
+func (b *Board) collision(i int, alive []int) Cause {
+	s := b.snakes[i]
+	head := s.Head()
+
+	switch {
+	case b.OutOfBounds(head.X, head.Y):
+		return CauseWall
+	case b.obstacles[head]:
+		return CauseObstacle
+	case s.HeadHitsBody():
+		return CauseSelf
+	}
+
+	for _, j := range alive {
+		other := b.snakes[j]
+		if j == i {
+			continue
+		}
+		body := other.body[:len(other.body)-1]
+		if slices.Contains(body, head) {
+			return CauseSnake
+		}
+		if other.Head() == head && len(other.body) >= len(s.body) {
+			return CauseHeadOn
+		}
+	}
+
+	return CauseNone
+}
+
+// Whether the game is over: the only snake is dead, or at most one of many is
+// left
+func (b *Board) over() bool {
+	alive := 0
+	for _, s := range b.snakes {
+		if s.alive() {
+			alive++
+		}
+	}
+	if len(b.snakes) == 1 {
+		return alive == 0
+	}
+	return alive <= 1
+}
+
+// Step advances the game by exactly one tick. The snake turns towards action
+// first, unless action is the zero vector or would reverse the snake onto
+// itself, in which case it keeps going the way it was heading. Any other
+// snakes keep going the way they're heading.
+func (b *Board) Step(action model.Vector) StepResult {
+	actions := make([]model.Vector, len(b.snakes))
+	actions[b.me] = action
+	return b.StepAll(actions)[b.me]
+}
+
+// StepAll advances the game by exactly one tick, turning every snake towards
+// its action first the way Step does. The results are per snake, each
+// rewarded as the board is seen from it. A snake that's dead is done.
+func (b *Board) StepAll(actions []model.Vector) []StepResult {
+	results := make([]StepResult, len(b.snakes))
+	if b.gameOver {
+		for i, s := range b.snakes {
+			results[i] = StepResult{Done: true, Cause: s.cause}
+		}
+		return results
+	}
+
+	dir := b.snake.direction
+	for i, s := range b.snakes {
+		if i < len(actions) && actions[i] != (model.Vector{}) && s.alive() {
+			s.ChangeDirection(actions[i])
+		}
+	}
+
+	// What the board looked like before the move, for the reward function.
+	// It shares the rng, but never places food.
+	before := *b
+	before.snakes = make([]*Snake, len(b.snakes))
+	for i, s := range b.snakes {
+		before.snakes[i] = s.Clone()
+	}
+	before.snake = before.snakes[b.me]
+	before.foods = slices.Clone(b.foods)
+
+	ate, eaten := b.moveSnakes()
+
+	if b.zobrist != nil && !b.gameOver {
+		if len(b.snakes) == 1 {
+			b.hash = b.zobrist.move(b.hash, &before, dir, b)
+		} else {
+			b.hash = b.zobrist.Hash(b)
+		}
+	}
+	b.cutShort(slices.Contains(ate, true))
+
+	for i, s := range b.snakes {
+		if !before.snakes[i].alive() {
+			results[i] = StepResult{Done: true, Cause: s.cause}
+			continue
+		}
+
+		ev := Event{Died: !s.alive(), Cause: s.cause}
+		if food := eaten[i]; food != nil {
+			ev.Ate, ev.Food, ev.Points = true, food.Kind, b.rule(food.Kind).Points
+		}
+		results[i] = StepResult{
+			Reward:  b.rewards.Reward(before.As(i), b.As(i), ev),
+			Done:    b.gameOver || !s.alive(),
+			AteFood: ate[i],
+			Cause:   s.cause,
+		}
+	}
+
+	return results
+}
+
+// Ends a game that would otherwise go on for good: one that has come back
+// round to a board it has been on since a snake last ate, or has taken the
+// most moves a game may take. Starvation, which comes of the move itself, is
+// checked first.
+func (b *Board) cutShort(ateFood bool) {
+	b.moves++
+	if b.gameOver {
+		return
+	}
+
+	if b.loops != nil {
+		if ateFood {
+			// Nothing from before eating can come round again
+			b.loops.Reset()
+		}
+		if _, _, cycle := b.loops.Observe(b.Hash()); cycle {
+			b.endGame(CauseLooped)
+			return
+		}
+	}
+
+	if b.maxMoves > 0 && b.moves >= b.maxMoves {
+		b.endGame(CauseTimedOut)
+	}
+}
+
+// Ends the game for every snake still alive, for the given cause
+func (b *Board) endGame(cause Cause) {
+	for _, s := range b.snakes {
+		if s.alive() {
+			s.cause = cause
+		}
+	}
+	b.gameOver = true
+}
+
+// Hash is the Zobrist hash of the board, for telling whether the game has been
+// here before. The first call works it out from scratch, from then on each
+// move keeps it up to date. It means nothing once the game is over.
+func (b *Board) Hash() uint64 {
+	if b.zobrist == nil {
+		b.zobrist = zobristFor(b.rows, b.cols)
+		b.hash = b.zobrist.Hash(b)
+	}
+	return b.hash
 }
 
 // Programmatically move the snake, rather than take keyboard input from player
@@ -126,23 +493,47 @@ func (b *Board) Move(dir model.Vector) {
 		return
 	}
 
-	b.snake.ChangeDirection(dir)
-	b.MoveSnake()
-	if b.snake.justAte {
-		b.snake.Move()
-	}
+	b.Step(dir)
 }
 
+// Points are indexed by row (X) and column (Y). A point off the board is out
+// of bounds even when the board wraps around, it's only that the snake never
+// gets there.
 func (b *Board) OutOfBounds(x, y int) bool {
-	return x > b.cols-1 || y > b.rows-1 || x < 0 || y < 0
+	return x > b.rows-1 || y > b.cols-1 || x < 0 || y < 0
 }
 
+// Where the snake would be after going steps cells in dir. On a board that
+// wraps around, that's back on the board past the opposite edge.
 func (b *Board) NextLocation(dir model.Vector, steps int) model.Point {
 	currentLocation := b.snake.Head()
 	nextX := currentLocation.X + dir.X*steps
 	nextY := currentLocation.Y + dir.Y*steps
 	nextLocation := model.Point{X: nextX, Y: nextY}
-	return nextLocation
+	return b.wrap(nextLocation)
+}
+
+// Brings p back onto a board that wraps around, and leaves it be otherwise
+func (b *Board) wrap(p model.Point) model.Point {
+	if b.walls != WrapAround {
+		return p
+	}
+	return model.Point{X: mod(p.X, b.rows), Y: mod(p.Y, b.cols)}
+}
+
+// The shortest way from a to c, the short way round on a board that wraps
+func (b *Board) offset(a, c model.Point) model.Vector {
+	dx, dy := c.X-a.X, c.Y-a.Y
+	if b.walls == WrapAround {
+		dx, dy = shortest(dx, b.rows), shortest(dy, b.cols)
+	}
+	return model.Vector{X: dx, Y: dy}
+}
+
+// The Manhattan distance from a to c, measured the short way round on a board
+// that wraps
+func (b *Board) distance(a, c model.Point) float64 {
+	return distance(model.Point{}, model.Point(b.offset(a, c)))
 }
 
 func (b *Board) MoveIsValid(nextLocation model.Point) bool {
@@ -151,42 +542,35 @@ func (b *Board) MoveIsValid(nextLocation model.Point) bool {
 }
 
 func (b *Board) MoveIsTerminal(point model.Point) bool {
-	return b.OutOfBounds(point.X, point.Y) || b.snake.HitsSnake(point)
+	point = b.wrap(point)
+	return b.OutOfBounds(point.X, point.Y) || b.obstacles[point] || b.hitsSnake(point) || b.poisonKills(point)
+}
+
+// Whether there's a poison at p the snake is too short to survive eating
+func (b *Board) poisonKills(p model.Point) bool {
+	i, ok := b.foodAt(p)
+	return ok && b.foods[i].Kind == PoisonFood && len(b.snake.body)-b.rule(PoisonFood).Shrink < 2
 }
 
+// Whether there's a food worth eating at nextLocation
 func (b *Board) MoveIsScoring(nextLocation model.Point) bool {
-	return b.food.X == nextLocation.X && b.food.Y == nextLocation.Y
+	i, ok := b.foodAt(nextLocation)
+	return ok && b.foods[i].edible()
 }
 
 func (b *Board) DistanceToFood(nextLocation model.Point) (currentDistance, nextDistance float64) {
 	currentLocation := b.snake.Head()
-	foodLocation := model.Point{X: b.food.X, Y: b.food.Y}
-	currentDistance = distance(currentLocation, foodLocation)
-	nextDistance = distance(nextLocation, foodLocation)
+	foodLocation := b.Food()
+	currentDistance = b.distance(currentLocation, foodLocation)
+	nextDistance = b.distance(nextLocation, foodLocation)
 	return currentDistance, nextDistance
 }
 
+// EvaluateAction is what moving in dir would be worth to the agent, and
+// whether it would end the game, tried out on a copy of the board
 func (b *Board) EvaluateAction(dir model.Vector) (float32, bool) {
-	nextLocation := b.NextLocation(dir, 1)
-	currDistanceToFood, nextDistanceToFood := b.DistanceToFood(nextLocation)
-
-	if b.MoveIsScoring(nextLocation) {
-		return 100.0, false
-
-	} else if b.MoveIsTerminal(nextLocation) {
-		return -100.0, true
-
-		// Reward the snake for moving closer to food
-	} else if nextDistanceToFood < currDistanceToFood {
-		return 2.0, false
-
-	} else if nextDistanceToFood > currDistanceToFood {
-		return -4.0, false
-
-		// Neutral move
-	} else {
-		return -1.0, false
-	}
+	result := b.Clone().Step(dir)
+	return result.Reward, result.Done
 }
 
 func (b *Board) DangerAhead() bool {
@@ -257,7 +641,7 @@ The state is an array of 11 values, representing:
   - Snake is moving right
   - Snake is moving up
   - Snake is moving down
-  - The food is on the left
+  - The food is on the left (the nearest worth eating, when there's more)
   - The food is on the right
   - The food is on the upper side
   - The food is on the lower side
@@ -271,10 +655,13 @@ func (b *Board) CurrentState() [11]float32 {
 	out[4] = boolToFloat32(b.snake.direction == model.Vector{X: 0, Y: 1})
 	out[5] = boolToFloat32(b.snake.direction == model.Vector{X: -1, Y: 0})
 	out[6] = boolToFloat32(b.snake.direction == model.Vector{X: 1, Y: 0})
-	out[7] = boolToFloat32(b.food.Y < b.snake.Head().Y)
-	out[8] = boolToFloat32(b.food.Y > b.snake.Head().Y)
-	out[9] = boolToFloat32(b.food.X < b.snake.Head().X)
-	out[10] = boolToFloat32(b.food.X > b.snake.Head().X)
+
+	// The short way round, when the board wraps
+	toFood := b.offset(b.snake.Head(), b.Food())
+	out[7] = boolToFloat32(toFood.Y < 0)
+	out[8] = boolToFloat32(toFood.Y > 0)
+	out[9] = boolToFloat32(toFood.X < 0)
+	out[10] = boolToFloat32(toFood.X > 0)
 
 	return out
 }
@@ -282,8 +669,7 @@ func (b *Board) CurrentState() [11]float32 {
 func (b *Board) NextState(dir model.Vector) [11]float32 {
 
 	// Create a clone of the board to evaluate branching state
-	clonedBoard := NewBoard(b.rows, b.cols, b.snake.Clone(), model.Point{X: b.food.X, Y: b.food.Y})
-	clonedBoard.points = b.points
+	clonedBoard := b.Clone()
 
 	// evaluate next state
 	clonedBoard.Move(dir)
@@ -292,24 +678,60 @@ func (b *Board) NextState(dir model.Vector) [11]float32 {
 	return nextState
 }
 
+// Clone copies the board so moves can be tried out on it. Food the clone
+// places comes from its own fixed seed, so trying moves out never changes
+// where the food turns up in the real game.
+func (b *Board) Clone() *Board {
+	clone := NewBoard(b.rows, b.cols, b.snake.Clone(), model.Point{})
+	clone.snakes = make([]*Snake, len(b.snakes))
+	for i, s := range b.snakes {
+		clone.snakes[i] = s.Clone()
+	}
+	clone.me, clone.snake = b.me, clone.snakes[b.me]
+	clone.foods = slices.Clone(b.foods)
+	clone.rules = b.rules
+	clone.boost = b.boost
+	clone.gameOver = b.gameOver
+	clone.growth = b.growth
+	clone.walls = b.walls
+	clone.obstacles = b.obstacles
+	clone.spots = b.spots
+	clone.nextSpot = b.nextSpot
+	clone.rewards = b.rewards
+	clone.starve = b.starve
+	clone.moves = b.moves
+	clone.maxMoves = b.maxMoves
+	clone.zobrist = b.zobrist
+	clone.hash = b.hash
+	return clone
+}
+
+// Print writes the board to stdout as plain text, see Game.WriteText
 func (b *Board) Print() {
-	boardView := make([][]int, b.rows, b.cols)
-	for rowIdx, row := range boardView {
-		for colIdx, _ := range row {
-			if b.snake.HitsSnake(model.Point{X: rowIdx, Y: colIdx}) {
-				fmt.Print("S")
-				boardView[rowIdx][colIdx] = 1 // Snake
-			} else if b.food.X == rowIdx && b.food.Y == colIdx {
-				fmt.Print("F")
-				boardView[rowIdx][colIdx] = 2 // Food
-			} else {
-				fmt.Print("0")
-				boardView[rowIdx][colIdx] = 0 // Empty
-			}
+	var sb strings.Builder
+	b.writeText(&sb, false)
+	os.Stdout.WriteString(sb.String())
+}
+
+// Level is the board as it is now, written down as a level to start from
+func (b *Board) Level() *Level {
+	l := &Level{
+		Rows:      b.rows,
+		Cols:      b.cols,
+		Snake:     append([]model.Point(nil), b.snake.body...),
+		Direction: b.snake.direction,
+	}
+	for _, f := range b.foods {
+		if f.Kind == NormalFood {
+			l.Food = append(l.Food, f.At)
 		}
-		fmt.Println()
 	}
+	for p := range b.obstacles {
+		l.Obstacles = append(l.Obstacles, p)
+	}
+	return l
 }
+
 func boolToFloat32(b bool) float32 {
 	if b {
 		return 1.0
@@ -320,3 +742,37 @@ func boolToFloat32(b bool) float32 {
 func distance(a, b model.Point) float64 {
 	return math.Abs(float64(a.X-b.X)) + math.Abs(float64(a.Y-b.Y))
 }
+
+// Heading is the direction from a cell to the next one along a body. Cells
+// further than one apart are neighbours round the edge of a board that wraps
+// around, so the step between them goes the other way.
+func Heading(from, next model.Point) model.Vector {
+	return model.Vector{X: step(next.X - from.X), Y: step(next.Y - from.Y)}
+}
+
+// The step between neighbouring cells that are d apart. Anything further than
+// one apart is a body wrapping round the board, stepping the other way.
+func step(d int) int {
+	switch {
+	case d > 1:
+		return -1
+	case d < -1:
+		return 1
+	}
+	return d
+}
+
+// x modulo n, never negative
+func mod(x, n int) int {
+	return (x%n + n) % n
+}
+
+// The shortest of d and the ways round a ring of n cells that end up in the
+// same place
+func shortest(d, n int) int {
+	d = mod(d, n)
+	if d > n/2 {
+		d -= n
+	}
+	return d
+}
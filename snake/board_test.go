@@ -1,6 +1,7 @@
 package snake
 
 import (
+	"math/rand"
 	"testing"
 
 	"github.com/casen/snakegame/model"
@@ -14,7 +15,9 @@ var (
 )
 
 func TestNewGameBoard(t *testing.T) {
-	board := NewGameBoard(10, 10)
+	cfg := DefaultConfig()
+	cfg.Rows, cfg.Cols = 10, 10
+	board := NewGameBoard(cfg, rand.New(rand.NewSource(1)))
 	if board == nil {
 		t.Errorf("NewGameBoard() = %v; want %v", board, "not nil")
 	}
@@ -75,8 +78,8 @@ func TestNextState(t *testing.T) {
 		t.Errorf("CurrentState mutated by NextState: %v, %v", board.CurrentState(), gotNext)
 	}
 
-	if board.points != 0 {
-		t.Errorf("Board points mutated by NextState: %v, expected 0", board.points)
+	if board.Score() != 0 {
+		t.Errorf("Board points mutated by NextState: %v, expected 0", board.Score())
 	}
 
 }
@@ -203,3 +206,137 @@ func TestMove(t *testing.T) {
 	}
 
 }
+
+func TestWrapAround(t *testing.T) {
+	// Heading north along the top row, with the food by the bottom edge
+	newBoard := func(walls WallMode) *Board {
+		board := NewBoard(
+			10,
+			10,
+			NewSnake([]model.Point{{X: 2, Y: 3}, {X: 1, Y: 3}, {X: 0, Y: 3}}, northVector),
+			model.Point{X: 8, Y: 3},
+		)
+		board.walls = walls
+		return board
+	}
+
+	solid, wrapped := newBoard(SolidWalls), newBoard(WrapAround)
+
+	if got := wrapped.NextLocation(northVector, 1); got != (model.Point{X: 9, Y: 3}) {
+		t.Errorf("NextLocation() = %v; want %v", got, model.Point{X: 9, Y: 3})
+	}
+	if !solid.DangerAhead() || wrapped.DangerAhead() {
+		t.Errorf("DangerAhead() = %v solid, %v wrapped; want true, false", solid.DangerAhead(), wrapped.DangerAhead())
+	}
+
+	// The food is 2 away going up through the edge, rather than 8 going down
+	if current, _ := wrapped.DistanceToFood(wrapped.NextLocation(northVector, 1)); current != 2 {
+		t.Errorf("DistanceToFood() = %v; want 2", current)
+	}
+	if state := wrapped.CurrentState(); state[9] != 1 || state[10] != 0 {
+		t.Errorf("CurrentState() = %v; want the food up", state)
+	}
+
+	if result := solid.Step(northVector); result.Cause != CauseWall {
+		t.Errorf("Step() on solid walls = %+v; want %v", result, CauseWall)
+	}
+
+	result := wrapped.Step(northVector)
+	if result.Done || wrapped.Head() != (model.Point{X: 9, Y: 3}) {
+		t.Errorf("Step() on a wrapping board = %+v, head %v; want alive at %v", result, wrapped.Head(), model.Point{X: 9, Y: 3})
+	}
+	if result.Reward != DefaultRewards.Closer {
+		t.Errorf("Step() reward = %v; want %v for closing in through the edge", result.Reward, DefaultRewards.Closer)
+	}
+}
+
+// A 10x10 board with two snakes on it and the food out of the way in the
+// bottom-right corner
+func newVersusBoard(a, b *Snake) *Board {
+	board := NewBoard(10, 10, a, model.Point{X: 9, Y: 9})
+	board.snakes = append(board.snakes, b)
+	return board
+}
+
+func TestSnakesCollide(t *testing.T) {
+	testCases := []struct {
+		name       string
+		a, b       *Snake
+		wantCauses [2]Cause
+	}{
+		{
+			"Head on, as long as each other",
+			NewSnake([]model.Point{{X: 5, Y: 1}, {X: 5, Y: 2}, {X: 5, Y: 3}}, eastVector),
+			NewSnake([]model.Point{{X: 5, Y: 7}, {X: 5, Y: 6}, {X: 5, Y: 5}}, westVector),
+			[2]Cause{CauseHeadOn, CauseHeadOn},
+		},
+		{
+			"Head on, the longer wins",
+			NewSnake([]model.Point{{X: 5, Y: 0}, {X: 5, Y: 1}, {X: 5, Y: 2}, {X: 5, Y: 3}}, eastVector),
+			NewSnake([]model.Point{{X: 5, Y: 7}, {X: 5, Y: 6}, {X: 5, Y: 5}}, westVector),
+			[2]Cause{CauseNone, CauseHeadOn},
+		},
+		{
+			"Head into a body",
+			NewSnake([]model.Point{{X: 5, Y: 1}, {X: 5, Y: 2}, {X: 5, Y: 3}}, eastVector),
+			NewSnake([]model.Point{{X: 4, Y: 4}, {X: 5, Y: 4}, {X: 6, Y: 4}}, southVector),
+			[2]Cause{CauseSnake, CauseNone},
+		},
+		{
+			"Swapping places",
+			NewSnake([]model.Point{{X: 5, Y: 1}, {X: 5, Y: 2}, {X: 5, Y: 3}}, eastVector),
+			NewSnake([]model.Point{{X: 5, Y: 6}, {X: 5, Y: 5}, {X: 5, Y: 4}}, westVector),
+			[2]Cause{CauseSnake, CauseSnake},
+		},
+		{
+			"Following a tail",
+			NewSnake([]model.Point{{X: 7, Y: 4}, {X: 6, Y: 4}, {X: 5, Y: 4}}, northVector),
+			NewSnake([]model.Point{{X: 4, Y: 4}, {X: 3, Y: 4}, {X: 2, Y: 4}}, northVector),
+			[2]Cause{CauseNone, CauseNone},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			board := newVersusBoard(tc.a, tc.b)
+			results := board.StepAll(nil)
+
+			for i, want := range tc.wantCauses {
+				if results[i].Cause != want || results[i].Done != (want != CauseNone || board.gameOver) {
+					t.Errorf("StepAll()[%d] = %+v; want %v", i, results[i], want)
+				}
+				if want != CauseNone && results[i].Reward != DefaultRewards.Death {
+					t.Errorf("StepAll()[%d] reward = %v; want %v", i, results[i].Reward, DefaultRewards.Death)
+				}
+			}
+			if want := tc.wantCauses[0] != CauseNone || tc.wantCauses[1] != CauseNone; board.gameOver != want {
+				t.Errorf("gameOver = %t; want %t", board.gameOver, want)
+			}
+		})
+	}
+}
+
+func TestSharedFood(t *testing.T) {
+	board := newVersusBoard(
+		NewSnake([]model.Point{{X: 1, Y: 0}, {X: 1, Y: 1}, {X: 1, Y: 2}}, eastVector),
+		NewSnake([]model.Point{{X: 8, Y: 6}, {X: 8, Y: 7}, {X: 8, Y: 8}}, eastVector),
+	)
+	board.foods[0].At = model.Point{X: 8, Y: 9}
+
+	// Only the second snake reaches the food, and only it scores
+	results := board.StepAll([]model.Vector{southVector, {}})
+	if results[0].AteFood || !results[1].AteFood || results[1].Reward != DefaultRewards.Food {
+		t.Errorf("StepAll() = %+v; want only the second snake fed", results)
+	}
+	if board.Score() != 0 || board.As(1).Score() != 1 {
+		t.Errorf("scores = %d, %d; want 0, 1", board.Score(), board.As(1).Score())
+	}
+	if board.foods[0].At == (model.Point{X: 8, Y: 9}) || board.hitsSnake(board.foods[0].At) {
+		t.Errorf("food respawned at %v; want a free cell", board.foods[0].At)
+	}
+
+	// Each snake sees the other as something to keep off
+	if !board.As(1).MoveIsTerminal(model.Point{X: 2, Y: 2}) || !board.MoveIsTerminal(model.Point{X: 8, Y: 8}) {
+		t.Errorf("MoveIsTerminal() = false for the other snake; want true")
+	}
+}
@@ -0,0 +1,287 @@
+package snake
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/casen/snakegame/model"
+)
+
+// Config describes the board and the rules a game is played with
+type Config struct {
+	Rows int
+	Cols int
+
+	// The starting body of the snake from tail to head, and the direction it
+	// sets off in
+	Snake     []model.Point
+	Direction model.Vector
+
+	// More snakes sharing the board, each playing for itself. The first snake
+	// is player 0, the rivals players 1 and up.
+	Rivals []Start
+
+	// How many cells the snake grows for each food it eats
+	Growth int
+
+	// What happens at the edges of the board
+	Walls WallMode
+
+	// Cells inside the board the snake can't go through, see Level
+	Obstacles []model.Point
+
+	// The kinds of food on the board and when each turns up
+	Foods []FoodRule
+
+	// Fixed spots the normal food turns up on, taking turns. Without any, it
+	// turns up on any free cell.
+	FoodSpots []model.Point
+
+	// When a snake that stopped eating is put out of its misery
+	Starvation StarveRule
+
+	// Whether the game ends, looped, once the board comes back round to one
+	// it has been on since the snake last ate
+	EndLoops bool
+
+	// The most moves a game may take. A game that gets there ends, timed out,
+	// however well the snake is doing. 0 lets games go on.
+	MaxMoves int
+
+	// How fast a frontend should step the game as the score goes up
+	Speed []SpeedStep
+
+	// What each move is worth to the agent
+	Rewards RewardFunc
+
+	// What the agent sees of the board
+	Encoder StateEncoder
+
+	// Agents turn left, go straight or turn right instead of picking one of
+	// the four Directions
+	RelativeActions bool
+
+	// Seeds food placement. The same seed always plays out the same way.
+	Seed int64
+}
+
+// Start is where a snake starts: its body from tail to head, and the
+// direction it sets off in
+type Start struct {
+	Snake     []model.Point
+	Direction model.Vector
+}
+
+// Mirrored is the start of a rival on the far side of the board from the
+// snake of the config, heading the opposite way
+func (c Config) Mirrored() Start {
+	start := Start{
+		Snake:     make([]model.Point, len(c.Snake)),
+		Direction: model.Vector{X: -c.Direction.X, Y: -c.Direction.Y},
+	}
+	for i, p := range c.Snake {
+		start.Snake[i] = model.Point{X: c.Rows - 1 - p.X, Y: c.Cols - 1 - p.Y}
+	}
+	return start
+}
+
+// WallMode is what the edges of the board do to a snake running into them
+type WallMode int
+
+const (
+	// The edges are walls, running into one ends the game
+	SolidWalls WallMode = iota
+
+	// The board wraps around: the snake leaves by one edge and comes back in
+	// by the opposite one
+	WrapAround
+)
+
+// StarveRule ends the game once the snake has gone more than Limit moves,
+// plus PerCell for every cell of its length, without eating. Longer snakes
+// need longer detours to reach the food safely. A Limit of 0 lets the snake
+// wander forever; to penalize wandering without ending the game, see
+// StarvationPenalty.
+type StarveRule struct {
+	Limit   int
+	PerCell int
+}
+
+// The most moves a snake of the given length may go without eating
+func (r StarveRule) limit(length int) int {
+	return r.Limit + r.PerCell*length
+}
+
+// SpeedStep is the interval between steps once the score reaches MinScore
+type SpeedStep struct {
+	MinScore int
+	Interval time.Duration
+}
+
+// The classic game: a 20x20 board with a snake of 4 starting in the top-left corner heading east
+func DefaultConfig() Config {
+	return Config{
+		Rows:      20,
+		Cols:      20,
+		Snake:     []model.Point{{X: 0, Y: 0}, {X: 0, Y: 1}, {X: 0, Y: 2}, {X: 0, Y: 3}},
+		Direction: model.Vector{X: 0, Y: 1},
+		Growth:    1,
+		Speed: []SpeedStep{
+			{MinScore: 0, Interval: time.Millisecond * 150},
+			{MinScore: 11, Interval: time.Millisecond * 125},
+			{MinScore: 21, Interval: time.Millisecond * 100},
+		},
+		Foods:   []FoodRule{FoodRules[NormalFood]},
+		Rewards: DefaultRewards,
+		Encoder: Features{},
+	}
+}
+
+// TrainingConfig is the classic game with the rules that keep an agent
+// playing on its own from getting stuck: a snake that stops eating starves, a
+// game going round in circles ends, and no game goes on past 10000 moves
+func TrainingConfig() Config {
+	cfg := DefaultConfig()
+	cfg.Starvation = StarveRule{Limit: 100, PerCell: 10}
+	cfg.EndLoops = true
+	cfg.MaxMoves = 10000
+	return cfg
+}
+
+// Validate reports the first thing wrong with the config, if anything
+func (c Config) Validate() error {
+	if c.Rows < 2 || c.Cols < 2 {
+		return fmt.Errorf("board must be at least 2x2, got %dx%d", c.Rows, c.Cols)
+	}
+
+	obstacles := make(map[model.Point]bool, len(c.Obstacles))
+	for _, p := range c.Obstacles {
+		if !c.onBoard(p) {
+			return fmt.Errorf("obstacle %v is off the %dx%d board", p, c.Rows, c.Cols)
+		}
+		obstacles[p] = true
+	}
+
+	for _, p := range c.FoodSpots {
+		if !c.onBoard(p) {
+			return fmt.Errorf("food spot %v is off the %dx%d board", p, c.Rows, c.Cols)
+		}
+		if obstacles[p] {
+			return fmt.Errorf("food spot %v is on an obstacle", p)
+		}
+	}
+
+	taken := make(map[model.Point]bool)
+	for _, start := range append([]Start{{c.Snake, c.Direction}}, c.Rivals...) {
+		if err := c.validateStart(start, obstacles, taken); err != nil {
+			return err
+		}
+	}
+
+	if c.Growth < 0 {
+		return fmt.Errorf("growth can't be negative, got %d", c.Growth)
+	}
+
+	if c.Walls != SolidWalls && c.Walls != WrapAround {
+		return fmt.Errorf("unknown wall mode %d", c.Walls)
+	}
+
+	if len(c.Foods) == 0 {
+		return errors.New("config needs at least one kind of food")
+	}
+	kinds := make(map[FoodKind]bool, len(c.Foods))
+	for _, r := range c.Foods {
+		if err := r.validate(); err != nil {
+			return err
+		}
+		if kinds[r.Kind] {
+			return fmt.Errorf("%v food has more than one rule", r.Kind)
+		}
+		kinds[r.Kind] = true
+	}
+
+	if c.Starvation.Limit < 0 || c.Starvation.PerCell < 0 {
+		return fmt.Errorf("starvation limits can't be negative, got %+v", c.Starvation)
+	}
+
+	if c.MaxMoves < 0 {
+		return fmt.Errorf("max moves can't be negative, got %d", c.MaxMoves)
+	}
+
+	if len(c.Speed) == 0 {
+		return errors.New("speed curve needs at least one step")
+	}
+	for i := 1; i < len(c.Speed); i++ {
+		if c.Speed[i].MinScore <= c.Speed[i-1].MinScore {
+			return errors.New("speed curve must be sorted by score")
+		}
+	}
+
+	if c.Rewards == nil {
+		return errors.New("config needs a reward function")
+	}
+
+	if c.Encoder == nil {
+		return errors.New("config needs a state encoder")
+	}
+
+	return nil
+}
+
+// Checks a snake starts somewhere it can, off the obstacles and the cells
+// already taken by other snakes
+func (c Config) validateStart(start Start, obstacles, taken map[model.Point]bool) error {
+	if len(start.Snake) < 2 {
+		return fmt.Errorf("snake must be at least 2 long, got %d", len(start.Snake))
+	}
+
+	for i, p := range start.Snake {
+		if !c.onBoard(p) {
+			return fmt.Errorf("snake cell %v is off the %dx%d board", p, c.Rows, c.Cols)
+		}
+		if obstacles[p] {
+			return fmt.Errorf("snake cell %v is on an obstacle", p)
+		}
+		if taken[p] {
+			return fmt.Errorf("snakes overlap at %v", p)
+		}
+		if i > 0 && distance(start.Snake[i-1], p) != 1 {
+			return fmt.Errorf("snake cells %v and %v are not adjacent", start.Snake[i-1], p)
+		}
+		for _, q := range start.Snake[:i] {
+			if p == q {
+				return fmt.Errorf("snake crosses itself at %v", p)
+			}
+		}
+	}
+	for _, p := range start.Snake {
+		taken[p] = true
+	}
+
+	if distance(model.Point{}, model.Point(start.Direction)) != 1 {
+		return fmt.Errorf("direction %v is not one of the four cardinals", start.Direction)
+	}
+
+	head, neck := start.Snake[len(start.Snake)-1], start.Snake[len(start.Snake)-2]
+	if head.X+start.Direction.X == neck.X && head.Y+start.Direction.Y == neck.Y {
+		return fmt.Errorf("direction %v points the snake back into itself", start.Direction)
+	}
+
+	return nil
+}
+
+func (c Config) onBoard(p model.Point) bool {
+	return p.X >= 0 && p.Y >= 0 && p.X < c.Rows && p.Y < c.Cols
+}
+
+// The interval between steps at the given score
+func (c Config) interval(points int) time.Duration {
+	interval := c.Speed[0].Interval
+	for _, step := range c.Speed {
+		if points >= step.MinScore {
+			interval = step.Interval
+		}
+	}
+	return interval
+}
@@ -0,0 +1,84 @@
+package snake
+
+import (
+	"testing"
+
+	"github.com/casen/snakegame/model"
+)
+
+func TestConfigValidate(t *testing.T) {
+	type validateCase struct {
+		name    string
+		edit    func(c *Config)
+		wantErr bool
+	}
+
+	testCases := []validateCase{
+		{"Default", func(c *Config) {}, false},
+		{"Small board", func(c *Config) { c.Rows, c.Cols = 10, 10 }, false},
+		{"Large board", func(c *Config) { c.Rows, c.Cols = 40, 40 }, false},
+		{"Snake off the board", func(c *Config) { c.Rows, c.Cols = 10, 3 }, true},
+		{"Snake too short", func(c *Config) { c.Snake = c.Snake[:1] }, true},
+		{"Snake not connected", func(c *Config) { c.Snake = []model.Point{{X: 0, Y: 0}, {X: 0, Y: 2}} }, true},
+		{"Heading into the neck", func(c *Config) { c.Direction = model.Vector{X: 0, Y: -1} }, true},
+		{"Diagonal direction", func(c *Config) { c.Direction = model.Vector{X: 1, Y: 1} }, true},
+		{"Wrapping around", func(c *Config) { c.Walls = WrapAround }, false},
+		{"Unknown wall mode", func(c *Config) { c.Walls = WallMode(7) }, true},
+		{"Obstacle off the board", func(c *Config) { c.Obstacles = []model.Point{{X: 20, Y: 0}} }, true},
+		{"Food on an obstacle", func(c *Config) { c.Obstacles, c.FoodSpots = []model.Point{{X: 5, Y: 5}}, []model.Point{{X: 5, Y: 5}} }, true},
+		{"Every kind of food", func(c *Config) {
+			c.Foods = []FoodRule{FoodRules[NormalFood], FoodRules[BonusFood], FoodRules[PoisonFood], FoodRules[SpeedFood]}
+		}, false},
+		{"No food", func(c *Config) { c.Foods = nil }, true},
+		{"Two rules for a food", func(c *Config) { c.Foods = append(c.Foods, FoodRules[NormalFood]) }, true},
+		{"Food spawning above its max", func(c *Config) { c.Foods = []FoodRule{{Kind: NormalFood, Spawn: SpawnPolicy{Min: 2, Max: 1}}} }, true},
+		{"Mirrored rival", func(c *Config) { c.Rivals = []Start{c.Mirrored()} }, false},
+		{"Rivals overlapping", func(c *Config) { c.Rivals = []Start{{c.Snake, c.Direction}} }, true},
+		{"Rival off the board", func(c *Config) {
+			c.Rivals = []Start{{[]model.Point{{X: 20, Y: 0}, {X: 20, Y: 1}}, eastVector}}
+		}, true},
+		{"Negative growth", func(c *Config) { c.Growth = -1 }, true},
+		{"No speed curve", func(c *Config) { c.Speed = nil }, true},
+		{"No encoder", func(c *Config) { c.Encoder = nil }, true},
+		{"No rewards", func(c *Config) { c.Rewards = nil }, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Snake = append([]model.Point(nil), cfg.Snake...)
+			tc.edit(&cfg)
+
+			err := cfg.Validate()
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Validate() = %v; want error %t", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestNonSquareBoard(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Rows, cfg.Cols = 5, 30
+	cfg.Growth = 3
+	game := newTestGame(t, cfg)
+
+	for i := 0; i < 100; i++ {
+		food := game.FoodLocation()
+		if food.X < 0 || food.X >= cfg.Rows || food.Y < 0 || food.Y >= cfg.Cols {
+			t.Fatalf("FoodLocation() = %v; want it on the %dx%d board", food, cfg.Rows, cfg.Cols)
+		}
+		game.Reset()
+	}
+
+	// Running east along the top row only hits the wall past the last column
+	for i := 0; i < cfg.Cols-4; i++ {
+		game.Step(eastVector)
+	}
+	if game.GameOver() {
+		t.Fatalf("GameOver() = true after %d steps east; want false", cfg.Cols-4)
+	}
+	if result := game.Step(eastVector); result.Cause != CauseWall {
+		t.Errorf("Step() = %+v; want the snake to hit the wall", result)
+	}
+}
@@ -0,0 +1,236 @@
+package snake
+
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/casen/snakegame/model"
+)
+
+// Zobrist hashes the state of a board: the body of every snake, where it's
+// heading and how much it still has to grow, the food and how long it stays,
+// and how long the game is sped up for. Each of those facts gets a random key,
+// and the hash is the xor of the keys of the facts that hold, so a move only
+// needs the few keys of what it changed.
+//
+// The body is hashed as the link from each of its cells to the next towards
+// the head, which pins down the whole layout. A snake coming back to the same
+// cell with its body laid out differently hashes differently.
+type Zobrist struct {
+	cols    int
+	links   [][len(Directions)]uint64 // by cell, then the direction to the next cell
+	heads   []uint64                  // by cell
+	foods   [][foodKinds]uint64       // by cell, then kind
+	dirs    [len(Directions)]uint64
+	growing [8]uint64 // growth left, anything past the last counts as the last
+	boost   uint64
+}
+
+// Every board of the same size shares the same keys, so their hashes compare
+const zobristSeed = 1
+
+var zobrists sync.Map // by [2]int{rows, cols}
+
+// The keys for boards of the given size, made once and shared from then on
+func zobristFor(rows, cols int) *Zobrist {
+	if z, ok := zobrists.Load([2]int{rows, cols}); ok {
+		return z.(*Zobrist)
+	}
+	z, _ := zobrists.LoadOrStore([2]int{rows, cols}, NewZobrist(rows, cols))
+	return z.(*Zobrist)
+}
+
+func NewZobrist(rows, cols int) *Zobrist {
+	rng := rand.New(rand.NewSource(zobristSeed))
+	cells := rows * cols
+
+	z := &Zobrist{
+		cols:  cols,
+		links: make([][len(Directions)]uint64, cells),
+		heads: make([]uint64, cells),
+		foods: make([][foodKinds]uint64, cells),
+	}
+	for i := range z.links {
+		for d := range z.links[i] {
+			z.links[i][d] = rng.Uint64()
+		}
+		z.heads[i] = rng.Uint64()
+		for k := range z.foods[i] {
+			z.foods[i][k] = rng.Uint64()
+		}
+	}
+	for i := range z.dirs {
+		z.dirs[i] = rng.Uint64()
+	}
+	for i := range z.growing {
+		z.growing[i] = rng.Uint64()
+	}
+	z.boost = rng.Uint64()
+
+	return z
+}
+
+// Hash works the hash of a board out from scratch
+func (z *Zobrist) Hash(b *Board) uint64 {
+	var h uint64
+	for i, s := range b.snakes {
+		if !s.alive() {
+			continue
+		}
+		// Each snake hashes differently, so two swapping places isn't the
+		// board it was
+		part := z.snake(s)
+		if i > 0 {
+			part = mix(part + uint64(i))
+		}
+		h ^= part
+	}
+	h ^= z.food(b.foods)
+	h ^= z.speed(b.boost)
+
+	return h
+}
+
+func (z *Zobrist) snake(s *Snake) uint64 {
+	body := s.body
+
+	var h uint64
+	for i := 0; i < len(body)-1; i++ {
+		h ^= z.link(body[i], body[i+1])
+	}
+	h ^= z.head(s.Head())
+	h ^= z.dir(s.direction)
+	h ^= z.grow(s.growing)
+
+	return h
+}
+
+// Updates h, the hash of before heading in dir, to the hash of after, one
+// move later. Only boards with a single snake are updated this way.
+func (z *Zobrist) move(h uint64, before *Board, dir model.Vector, after *Board) uint64 {
+	oldBody, newBody := before.snake.body, after.snake.body
+	oldHead, newHead := before.snake.Head(), after.snake.Head()
+
+	// A snake of a single cell, all coiled up, has no links to move along
+	if len(oldBody) < 2 {
+		return z.Hash(after)
+	}
+
+	switch len(newBody) - len(oldBody) {
+	case 0:
+		h ^= z.link(oldBody[0], oldBody[1])
+	case 1:
+	default:
+		// A poison took a bite out of the snake, it's quicker to start over
+		return z.Hash(after)
+	}
+	h ^= z.head(oldHead) ^ z.head(newHead)
+	h ^= z.link(oldHead, newHead)
+
+	h ^= z.dir(dir) ^ z.dir(after.snake.direction)
+	h ^= z.food(before.foods) ^ z.food(after.foods)
+	h ^= z.grow(before.snake.growing) ^ z.grow(after.snake.growing)
+	h ^= z.speed(before.boost) ^ z.speed(after.boost)
+
+	return h
+}
+
+func (z *Zobrist) cell(p model.Point) int {
+	return p.X*z.cols + p.Y
+}
+
+// The key of the body running from a to the next cell b
+func (z *Zobrist) link(a, b model.Point) uint64 {
+	return z.links[z.cell(a)][directionIndex(Heading(a, b))]
+}
+
+func (z *Zobrist) head(p model.Point) uint64 {
+	return z.heads[z.cell(p)]
+}
+
+// The key of the food on the board. A food going away soon is keyed apart
+// from the same food with longer to go.
+func (z *Zobrist) food(foods []Food) uint64 {
+	var h uint64
+	for _, f := range foods {
+		key := z.foods[z.cell(f.At)][f.Kind]
+		if f.TTL > 0 {
+			key = mix(key + uint64(f.TTL))
+		}
+		h ^= key
+	}
+	return h
+}
+
+func (z *Zobrist) speed(boost int) uint64 {
+	if boost == 0 {
+		return 0
+	}
+	return mix(z.boost + uint64(boost))
+}
+
+// Scrambles x into a key of its own, the finalizer of splitmix64
+func mix(x uint64) uint64 {
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+func (z *Zobrist) dir(v model.Vector) uint64 {
+	if v == (model.Vector{}) {
+		// A snake that hasn't moved yet has no direction, see Position
+		return 0
+	}
+	return z.dirs[directionIndex(v)]
+}
+
+func (z *Zobrist) grow(growing int) uint64 {
+	return z.growing[min(growing, len(z.growing)-1)]
+}
+
+// The index of v in Directions
+func directionIndex(v model.Vector) int {
+	for i, d := range Directions {
+		if d == v {
+			return i
+		}
+	}
+	panic("not one of the four directions")
+}
+
+// CycleDetector spots a game going round in circles, one board hash at a
+// time. A board the game comes back to will come round again and again, as
+// long as the player always makes the same move on the same board.
+//
+// It remembers every hash since the last Reset. Eating changes the food and
+// the length of the snake for good, so nothing before it can come round
+// again, which makes eating a good time to reset.
+type CycleDetector struct {
+	seen map[uint64]int // the tick each hash was first seen at
+	tick int
+}
+
+func NewCycleDetector() *CycleDetector {
+	return &CycleDetector{seen: make(map[uint64]int)}
+}
+
+// Observe records the hash of the board at the next tick. Once the board is
+// one seen before, it reports the tick the cycle starts at, counting from the
+// last Reset, and how many ticks it takes to come round.
+func (d *CycleDetector) Observe(hash uint64) (start, period int, cycle bool) {
+	tick := d.tick
+	d.tick++
+
+	if first, ok := d.seen[hash]; ok {
+		return first, tick - first, true
+	}
+	d.seen[hash] = tick
+
+	return 0, 0, false
+}
+
+// Reset forgets every board seen so far
+func (d *CycleDetector) Reset() {
+	clear(d.seen)
+	d.tick = 0
+}
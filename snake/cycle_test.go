@@ -0,0 +1,124 @@
+package snake
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/casen/snakegame/model"
+)
+
+func TestHashStaysInStepWithTheBoard(t *testing.T) {
+	for _, walls := range []WallMode{SolidWalls, WrapAround} {
+		cfg := DefaultConfig()
+		cfg.Rows, cfg.Cols = 8, 8
+		cfg.Growth = 3
+		cfg.Walls = walls
+		cfg.Foods = []FoodRule{FoodRules[NormalFood], FoodRules[BonusFood], FoodRules[PoisonFood], FoodRules[SpeedFood]}
+		game := newTestGame(t, cfg)
+		rng := rand.New(rand.NewSource(1))
+
+		for games := 0; games < 20; games++ {
+			game.Reset()
+			game.Hash()
+			for !game.GameOver() {
+				game.Step(Directions[rng.Intn(len(Directions))])
+				if game.GameOver() {
+					break
+				}
+				if got, want := game.Hash(), game.board.zobrist.Hash(game.board); got != want {
+					t.Fatalf("walls %v: Hash() = %x after a move; want %x, as worked out from scratch", walls, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestHashSeesTheBodyLayout(t *testing.T) {
+	// The same cells, the same head heading the same way, the same food, but
+	// the body runs the other way round the square
+	//
+	//   T H      B H
+	//   B B      T B
+	food := model.Point{X: 5, Y: 5}
+	clockwise := NewBoard(10, 10, NewSnake([]model.Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}, {X: 0, Y: 1}}, eastVector), food)
+	counter := NewBoard(10, 10, NewSnake([]model.Point{{X: 1, Y: 1}, {X: 1, Y: 0}, {X: 0, Y: 0}, {X: 0, Y: 1}}, eastVector), food)
+	again := NewBoard(10, 10, NewSnake([]model.Point{{X: 1, Y: 1}, {X: 1, Y: 0}, {X: 0, Y: 0}, {X: 0, Y: 1}}, eastVector), food)
+
+	if clockwise.Hash() == counter.Hash() {
+		t.Errorf("Hash() is the same for bodies laid out differently")
+	}
+	if counter.Hash() != again.Hash() {
+		t.Errorf("Hash() = %x and %x for the same board; want the same", counter.Hash(), again.Hash())
+	}
+}
+
+func TestCycleDetector(t *testing.T) {
+	// A snake of 4 turns off its row into a 2x2 square and circles it. The
+	// first two moves bring it into the loop, after which it's back on the
+	// same board every 4 moves.
+	board := NewBoard(
+		10,
+		10,
+		NewSnake([]model.Point{{X: 4, Y: 7}, {X: 4, Y: 6}, {X: 4, Y: 5}, {X: 4, Y: 4}}, westVector),
+		model.Point{X: 0, Y: 0},
+	)
+	circle := []model.Vector{southVector, eastVector, northVector, westVector}
+
+	detector := NewCycleDetector()
+	for tick := 0; tick < 20; tick++ {
+		start, period, cycle := detector.Observe(board.Hash())
+		if cycle {
+			if start != 2 || period != 4 || tick != 6 {
+				t.Errorf("Observe() at tick %d = %d, %d; want a cycle at tick 6 starting at 2 with period 4", tick, start, period)
+			}
+			return
+		}
+		board.Step(circle[tick%4])
+	}
+	t.Errorf("Observe() never found the cycle")
+}
+
+func TestCycleDetectorReset(t *testing.T) {
+	detector := NewCycleDetector()
+	detector.Observe(1)
+	detector.Observe(2)
+	detector.Reset()
+
+	if _, _, cycle := detector.Observe(1); cycle {
+		t.Errorf("Observe() found a cycle with a hash seen before Reset()")
+	}
+	if start, period, cycle := detector.Observe(1); !cycle || start != 0 || period != 1 {
+		t.Errorf("Observe() = %d, %d, %t; want 0, 1, true", start, period, cycle)
+	}
+}
+
+func TestHashOfASingleCellSnake(t *testing.T) {
+	// A snake that hasn't grown out of its first cell, the way Battlesnake
+	// starts its games, circling a 2x2 square
+	game := newTestGame(t, DefaultConfig())
+	err := game.Load(Position{
+		Rows:   6,
+		Cols:   6,
+		Snakes: []SnakePosition{{Body: []model.Point{{X: 2, Y: 2}}, Direction: eastVector}},
+		Foods:  []Food{{Kind: NormalFood, At: model.Point{X: 5, Y: 0}}},
+	})
+	if err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	circle := []model.Vector{eastVector, southVector, westVector, northVector}
+
+	detector := NewCycleDetector()
+	for tick := 0; tick < 12; tick++ {
+		if _, period, cycle := detector.Observe(game.Hash()); cycle {
+			if period != 4 {
+				t.Errorf("Observe() at tick %d found a cycle of period %d; want 4", tick, period)
+			}
+			return
+		}
+		game.Step(circle[tick%4])
+		if got, want := game.Hash(), game.board.zobrist.Hash(game.board); got != want {
+			t.Fatalf("Hash() = %x after move %d; want %x, as worked out from scratch", got, tick, want)
+		}
+	}
+	t.Errorf("Observe() never found the cycle")
+}
@@ -0,0 +1,50 @@
+//go:build !headless
+
+package snake
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// The screen is just big enough to hold the board
+func (g *Game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	width := g.cellSize()
+	return g.config.Cols * width, g.config.Rows * width
+}
+
+func (g *Game) Draw(screen *ebiten.Image) {
+	screen.Fill(backgroundColor)
+	if g.board.gameOver {
+		ebitenutil.DebugPrint(screen, "Game Over. "+g.scores())
+	} else {
+		width := g.cellSize()
+
+		for p := range g.board.obstacles {
+			vector.DrawFilledRect(screen, float32(p.Y*width), float32(p.X*width), float32(width), float32(width), obstacleColor, true)
+		}
+		for i, s := range g.board.snakes {
+			if !s.alive() {
+				continue
+			}
+			for _, p := range s.body {
+				vector.DrawFilledRect(screen, float32(p.Y*width), float32(p.X*width), float32(width), float32(width), snakeColors[i%len(snakeColors)], true)
+			}
+		}
+		for _, f := range g.board.foods {
+			vector.DrawFilledRect(screen, float32(f.At.Y*width), float32(f.At.X*width), float32(width), float32(width), foodColors[f.Kind], true)
+		}
+		if g.config.Walls == WrapAround {
+			g.drawPortals(screen)
+		}
+		ebitenutil.DebugPrint(screen, g.scores())
+	}
+}
+
+// Marks the edges of a board that wraps around, so the player can tell the
+// snake goes through them
+func (g *Game) drawPortals(screen *ebiten.Image) {
+	w, h := screen.Bounds().Dx(), screen.Bounds().Dy()
+	vector.StrokeRect(screen, portalWidth/2, portalWidth/2, float32(w-portalWidth), float32(h-portalWidth), portalWidth, portalColor, true)
+}
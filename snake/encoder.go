@@ -0,0 +1,242 @@
+package snake
+
+import (
+	"github.com/casen/snakegame/model"
+)
+
+// StateEncoder turns a board into what the agent observes of it. Every value
+// of an observation lies between 0 and 1.
+type StateEncoder interface {
+	Encode(b *Board) model.Observation
+
+	// The shape of the observations of a rows x cols board
+	Shape(rows, cols int) []int
+}
+
+// Features are the 11 hand-crafted booleans of Board.CurrentState: danger
+// ahead, right and left, the direction of the snake and where the food is
+type Features struct{}
+
+func (Features) Encode(b *Board) model.Observation {
+	state := b.CurrentState()
+	return state[:]
+}
+
+func (Features) Shape(rows, cols int) []int {
+	return []int{11}
+}
+
+// Grid is the whole board, one channel each for the body of the snake, its
+// head and the food, with a 1 for every cell they occupy. Obstacles, poison
+// and the other snakes go in with the body, being things to keep off.
+type Grid struct{}
+
+const (
+	gridBody = iota
+	gridHead
+	gridFood
+	gridChannels
+)
+
+func (Grid) Encode(b *Board) model.Observation {
+	cells := b.rows * b.cols
+	out := make(model.Observation, gridChannels*cells)
+
+	mark := func(channel int, p model.Point) {
+		out[channel*cells+p.X*b.cols+p.Y] = 1
+	}
+	body := b.snake.body
+	for _, p := range body[:len(body)-1] {
+		mark(gridBody, p)
+	}
+	for p := range b.obstacles {
+		mark(gridBody, p)
+	}
+	for _, s := range b.others() {
+		for _, p := range s.body {
+			mark(gridBody, p)
+		}
+	}
+	for _, f := range b.foods {
+		if f.edible() {
+			mark(gridFood, f.At)
+		} else {
+			mark(gridBody, f.At)
+		}
+	}
+	mark(gridHead, b.snake.Head())
+
+	return out
+}
+
+func (Grid) Shape(rows, cols int) []int {
+	return []int{gridChannels, rows, cols}
+}
+
+// Raycasts look out from the head in the 8 compass directions, seeing how far
+// off the wall, the body and the food are in each, as 1/distance or 0 when
+// there's none in sight. An obstacle or a poison is a wall the ray stops at,
+// and another snake counts as body. The direction of the snake follows,
+// one-hot in the order of Directions.
+type Raycasts struct{}
+
+// Clockwise from north
+var rayDirections = [8]model.Vector{
+	{X: -1, Y: 0}, {X: -1, Y: 1}, {X: 0, Y: 1}, {X: 1, Y: 1},
+	{X: 1, Y: 0}, {X: 1, Y: -1}, {X: 0, Y: -1}, {X: -1, Y: -1},
+}
+
+func (Raycasts) Encode(b *Board) model.Observation {
+	out := make(model.Observation, 0, 3*len(rayDirections)+len(Directions))
+
+	head := b.snake.Head()
+	for _, dir := range rayDirections {
+		var wall, body, food float32
+		p := head
+		for steps := 1; ; steps++ {
+			// On a board that wraps, the ray goes on round until it comes
+			// back to the head, without ever seeing a wall
+			p = b.wrap(model.Point{X: p.X + dir.X, Y: p.Y + dir.Y})
+			i, isFood := b.foodAt(p)
+			if b.OutOfBounds(p.X, p.Y) || b.obstacles[p] || (isFood && !b.foods[i].edible()) {
+				wall = 1 / float32(steps)
+				break
+			}
+			if p == head {
+				break
+			}
+			if body == 0 && b.hitsSnake(p) {
+				body = 1 / float32(steps)
+			}
+			if food == 0 && isFood {
+				food = 1 / float32(steps)
+			}
+		}
+		out = append(out, wall, body, food)
+	}
+
+	for _, dir := range Directions {
+		out = append(out, boolToFloat32(b.snake.direction == dir))
+	}
+
+	return out
+}
+
+func (Raycasts) Shape(rows, cols int) []int {
+	return []int{3*len(rayDirections) + len(Directions)}
+}
+
+// FloodFill adds to the 11 Features how much room the snake would have left
+// after moving in each of Directions: the share of the free cells it could
+// still reach from there, or 0 when the move kills it. That's what tells a
+// snake about to coil onto itself apart from one with space to spare.
+type FloodFill struct{}
+
+func (FloodFill) Encode(b *Board) model.Observation {
+	out := Features{}.Encode(b)
+
+	free := b.rows*b.cols - len(b.snake.body) - len(b.obstacles)
+	for _, s := range b.others() {
+		free -= len(s.body)
+	}
+	for _, dir := range Directions {
+		var room float32
+		if free > 0 {
+			room = float32(b.reachable(b.NextLocation(dir, 1))) / float32(free)
+		}
+		out = append(out, room)
+	}
+
+	return out
+}
+
+func (FloodFill) Shape(rows, cols int) []int {
+	return []int{11 + len(Directions)}
+}
+
+// Counts the cells the snake could reach from start without going through a
+// wall, an obstacle or a snake as they are now
+func (b *Board) reachable(start model.Point) int {
+	if b.MoveIsTerminal(start) {
+		return 0
+	}
+
+	seen := map[model.Point]bool{start: true}
+	queue := []model.Point{start}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, dir := range Directions {
+			next := b.wrap(model.Point{X: p.X + dir.X, Y: p.Y + dir.Y})
+			if !seen[next] && !b.MoveIsTerminal(next) {
+				seen[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	return len(seen)
+}
+
+// BoardTensor is the board as the channels of an image, for a convolutional
+// network: the head, the body, the food and the walls, obstacles, poison and
+// other snakes included. The body fades from 1 behind the head towards the
+// tail, telling the network how soon each cell clears. The board is framed by
+// a ring of walls, so the walls channel shows where the board ends. A board
+// that wraps around has no walls, so the frame is left empty.
+type BoardTensor struct{}
+
+const (
+	tensorHead = iota
+	tensorBody
+	tensorFood
+	tensorWalls
+	tensorChannels
+)
+
+func (BoardTensor) Encode(b *Board) model.Observation {
+	rows, cols := b.rows+2, b.cols+2
+	cells := rows * cols
+	out := make(model.Observation, tensorChannels*cells)
+
+	// Cells of the board are shifted by one to make room for the frame
+	at := func(channel int, p model.Point) *float32 {
+		return &out[channel*cells+(p.X+1)*cols+p.Y+1]
+	}
+
+	for x := -1; x <= b.rows; x++ {
+		for y := -1; y <= b.cols; y++ {
+			if b.walls == SolidWalls && b.OutOfBounds(x, y) {
+				*at(tensorWalls, model.Point{X: x, Y: y}) = 1
+			}
+		}
+	}
+
+	for p := range b.obstacles {
+		*at(tensorWalls, p) = 1
+	}
+	for _, s := range b.others() {
+		for _, p := range s.body {
+			*at(tensorWalls, p) = 1
+		}
+	}
+
+	body := b.snake.body
+	for i, p := range body[:len(body)-1] {
+		*at(tensorBody, p) = float32(i+1) / float32(len(body)-1)
+	}
+	*at(tensorHead, b.snake.Head()) = 1
+	for _, f := range b.foods {
+		if f.edible() {
+			*at(tensorFood, f.At) = 1
+		} else {
+			*at(tensorWalls, f.At) = 1
+		}
+	}
+
+	return out
+}
+
+func (BoardTensor) Shape(rows, cols int) []int {
+	return []int{tensorChannels, rows + 2, cols + 2}
+}
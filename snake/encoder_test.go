@@ -0,0 +1,168 @@
+package snake
+
+import (
+	"testing"
+
+	"github.com/casen/snakegame/model"
+)
+
+func TestEncoderShapes(t *testing.T) {
+	encoders := map[string]StateEncoder{
+		"Features":    Features{},
+		"Grid":        Grid{},
+		"Raycasts":    Raycasts{},
+		"FloodFill":   FloodFill{},
+		"BoardTensor": BoardTensor{},
+	}
+
+	for name, enc := range encoders {
+		t.Run(name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Rows, cfg.Cols = 7, 9
+			cfg.Encoder = enc
+			env := NewEnv(newTestGame(t, cfg))
+
+			obs := env.Reset()
+			if want := env.ObservationSpace().Size(); len(obs) != want {
+				t.Errorf("len(Encode()) = %d; want %d from Shape()", len(obs), want)
+			}
+			for i, v := range obs {
+				if v < 0 || v > 1 {
+					t.Errorf("Encode()[%d] = %v; want between 0 and 1", i, v)
+				}
+			}
+		})
+	}
+}
+
+func TestGridEncoder(t *testing.T) {
+	board := NewBoard(
+		3,
+		4,
+		NewSnake([]model.Point{{X: 0, Y: 0}, {X: 0, Y: 1}}, eastVector),
+		model.Point{X: 2, Y: 3},
+	)
+
+	got := Grid{}.Encode(board)
+	want := model.Observation{
+		1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, // body
+		0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, // head
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, // food
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Encode() = %v; want %v", got, want)
+		}
+	}
+}
+
+func TestRaycastEncoder(t *testing.T) {
+	// Heading east along the top row, food 3 cells ahead
+	board := NewBoard(
+		10,
+		10,
+		NewSnake([]model.Point{{X: 0, Y: 0}, {X: 0, Y: 1}, {X: 0, Y: 2}}, eastVector),
+		model.Point{X: 0, Y: 5},
+	)
+	got := Raycasts{}.Encode(board)
+
+	north, east, west := got[0:3], got[6:9], got[18:21]
+	if north[0] != 1 {
+		t.Errorf("wall to the north = %v; want 1, right next to the head", north[0])
+	}
+	if east[0] != float32(1)/8 || east[2] != float32(1)/3 {
+		t.Errorf("wall, food to the east = %v, %v; want 1/8, 1/3", east[0], east[2])
+	}
+	if west[1] != 1 {
+		t.Errorf("body to the west = %v; want 1, right behind the head", west[1])
+	}
+	if dir := got[24:]; dir[0] != 1 || dir[1] != 0 || dir[2] != 0 || dir[3] != 0 {
+		t.Errorf("direction = %v; want east", dir)
+	}
+}
+
+func TestRaycastEncoderWrapsAround(t *testing.T) {
+	board := NewBoard(
+		10,
+		10,
+		NewSnake([]model.Point{{X: 0, Y: 0}, {X: 0, Y: 1}, {X: 0, Y: 2}}, eastVector),
+		model.Point{X: 0, Y: 5},
+	)
+	board.walls = WrapAround
+	got := Raycasts{}.Encode(board)
+
+	for i := 0; i < len(rayDirections); i++ {
+		if got[3*i] != 0 {
+			t.Errorf("wall along ray %d = %v; want 0, there are none", i, got[3*i])
+		}
+	}
+	// Going east, the ray passes the food and comes round to the tail
+	if east := got[6:9]; east[1] != float32(1)/8 || east[2] != float32(1)/3 {
+		t.Errorf("body, food to the east = %v, %v; want 1/8, 1/3", east[1], east[2])
+	}
+}
+
+func TestFloodFillEncoder(t *testing.T) {
+	// The body cuts the top row in two: west of the head is a pocket of two
+	// cells, east of it the rest of the board
+	//
+	//   . . H . .
+	//   B B B . .
+	//   . . . . .
+	board := NewBoard(
+		3,
+		5,
+		NewSnake([]model.Point{{X: 1, Y: 0}, {X: 1, Y: 1}, {X: 1, Y: 2}, {X: 0, Y: 2}}, northVector),
+		model.Point{X: 2, Y: 4},
+	)
+	got := FloodFill{}.Encode(board)[11:]
+
+	free := float32(15 - 4)
+	want := [4]float32{9 / free, 0, 0, 2 / free} // E, N, S, W
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("room after moving %v = %v; want %v", Directions[i], got[i], want[i])
+		}
+	}
+}
+
+func TestBoardTensorEncoder(t *testing.T) {
+	board := NewBoard(
+		2,
+		3,
+		NewSnake([]model.Point{{X: 0, Y: 0}, {X: 0, Y: 1}, {X: 0, Y: 2}}, eastVector),
+		model.Point{X: 1, Y: 2},
+	)
+
+	got := BoardTensor{}.Encode(board)
+	want := model.Observation{
+		// head
+		0, 0, 0, 0, 0,
+		0, 0, 0, 1, 0,
+		0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0,
+		// body, the tail fading out
+		0, 0, 0, 0, 0,
+		0, 0.5, 1, 0, 0,
+		0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0,
+		// food
+		0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0,
+		0, 0, 0, 1, 0,
+		0, 0, 0, 0, 0,
+		// walls
+		1, 1, 1, 1, 1,
+		1, 0, 0, 0, 1,
+		1, 0, 0, 0, 1,
+		1, 1, 1, 1, 1,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("len(Encode()) = %d; want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Encode() = %v; want %v", got, want)
+		}
+	}
+}
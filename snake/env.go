@@ -0,0 +1,152 @@
+package snake
+
+import (
+	"reflect"
+	"strconv"
+
+	"github.com/casen/snakegame/model"
+)
+
+// Directions are the moves of the snake, indexed by model.Action
+var Directions = [4]model.Vector{
+	{X: 0, Y: 1},  // E
+	{X: -1, Y: 0}, // N
+	{X: 1, Y: 0},  // S
+	{X: 0, Y: -1}, // W
+}
+
+// ActionDirection is the direction the snake turns to for an action
+func ActionDirection(a model.Action) model.Vector {
+	return Directions[a]
+}
+
+// The actions of the relative action space, turning from wherever the snake
+// is heading. There's no reverse to waste a choice on, and the same situation
+// calls for the same action whichever way the board is turned.
+const (
+	TurnLeft model.Action = iota
+	Straight
+	TurnRight
+	relativeActions
+)
+
+// Turn is the direction the snake heads in after a relative action, coming
+// from dir
+func Turn(dir model.Vector, a model.Action) model.Vector {
+	switch a {
+	case TurnLeft:
+		return model.Vector{X: -dir.Y, Y: dir.X}
+	case TurnRight:
+		return model.Vector{X: dir.Y, Y: -dir.X}
+	default:
+		return dir
+	}
+}
+
+// Env lets agents play the game through the generic model.Env interface,
+// observing it through the encoder of the game's config
+type Env struct {
+	game    *Game
+	player  int
+	encoder StateEncoder
+}
+
+func NewEnv(game *Game) *Env {
+	return NewPlayerEnv(game, 0)
+}
+
+// NewPlayerEnv lets an agent play one of the snakes of a game with several.
+// Stepping the env moves the other snakes the way they're heading, so games
+// where each snake has its own player step the game itself, see Game.StepAll.
+func NewPlayerEnv(game *Game, player int) *Env {
+	return &Env{game: game, player: player, encoder: game.config.Encoder}
+}
+
+// The board as the player sees it
+func (e *Env) board() *Board {
+	return e.game.board.As(e.player)
+}
+
+func (e *Env) Reset() model.Observation {
+	e.game.Reset()
+	return e.Observe()
+}
+
+func (e *Env) Step(action model.Action) (model.Observation, float32, bool, model.Info) {
+	actions := make([]model.Vector, e.game.Players())
+	actions[e.player] = e.direction(e.board(), action)
+	result := e.game.StepAll(actions)[e.player]
+	return e.Observe(), result.Reward, result.Done, e.info(e.board(), result)
+}
+
+// Peek plays the action on a copy of the board, leaving the game untouched
+func (e *Env) Peek(action model.Action) (model.Observation, float32, bool, model.Info) {
+	board := e.board().Clone()
+	result := board.Step(e.direction(board, action))
+	return e.encoder.Encode(board), result.Reward, result.Done, e.info(board, result)
+}
+
+// Observe returns the observation of the game as it is now
+func (e *Env) Observe() model.Observation {
+	return e.encoder.Encode(e.board())
+}
+
+// Direction is the direction an action sends the snake in as the game is now
+func (e *Env) Direction(action model.Action) model.Vector {
+	return e.direction(e.board(), action)
+}
+
+func (e *Env) direction(b *Board, action model.Action) model.Vector {
+	if e.game.config.RelativeActions {
+		return Turn(b.snake.direction, action)
+	}
+	return ActionDirection(action)
+}
+
+func (e *Env) ActionSpace() model.Discrete {
+	if e.game.config.RelativeActions {
+		return model.Discrete{N: int(relativeActions)}
+	}
+	return model.Discrete{N: len(Directions)}
+}
+
+// LegalActions are every direction but the reverse of the snake's heading,
+// which Step turns into keeping straight on, or every turn when the actions
+// are relative
+func (e *Env) LegalActions() []model.Action {
+	b := e.board()
+	var legal []model.Action
+	for a := 0; a < e.ActionSpace().N; a++ {
+		if e.game.config.RelativeActions || !b.snake.OppositeDir(Directions[a]) {
+			legal = append(legal, model.Action(a))
+		}
+	}
+	return legal
+}
+
+// Variant is what an agent trained on the env depends on: the encoder it sees
+// the board through, whether its actions are relative and whether the board
+// wraps around
+func (e *Env) Variant() map[string]string {
+	walls := "solid"
+	if e.game.config.Walls == WrapAround {
+		walls = "wrap"
+	}
+	return map[string]string{
+		"encoder":  reflect.TypeOf(e.encoder).Name(),
+		"relative": strconv.FormatBool(e.game.config.RelativeActions),
+		"walls":    walls,
+	}
+}
+
+func (e *Env) ObservationSpace() model.Box {
+	return model.Box{Shape: e.encoder.Shape(e.game.config.Rows, e.game.config.Cols), Low: 0, High: 1}
+}
+
+func (e *Env) info(b *Board, result StepResult) model.Info {
+	return model.Info{
+		Score:  b.Score(),
+		Scored: result.AteFood,
+		Died:   result.Done && result.Cause != CauseNone,
+	}
+}
@@ -0,0 +1,123 @@
+package snake
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/casen/snakegame/model"
+)
+
+func TestEnvPeekLeavesGameAlone(t *testing.T) {
+	env := NewEnv(newTestGame(t, DefaultConfig()))
+	before := env.Observe()
+	head := env.game.CurrentLocation()
+
+	// Heading north from the top row runs into the wall
+	_, reward, done, _ := env.Peek(model.Action(1))
+	if !done || reward != DefaultRewards.Death {
+		t.Errorf("Peek(N) = %v, %t; want %v, true", reward, done, DefaultRewards.Death)
+	}
+
+	if env.game.GameOver() || env.game.CurrentLocation() != head {
+		t.Errorf("Peek(N) moved the real snake to %v", env.game.CurrentLocation())
+	}
+
+	obs, _, done, _ := env.Step(model.Action(0))
+	if done {
+		t.Fatalf("Step(E) ended the game")
+	}
+	state := env.game.CurrentState()
+	if len(obs) != len(state) || len(before) != len(state) {
+		t.Fatalf("Step(E) observation has %d values; want %d", len(obs), len(state))
+	}
+	for i := range state {
+		if obs[i] != state[i] {
+			t.Errorf("Step(E) observation = %v; want %v", obs, state)
+			break
+		}
+	}
+}
+
+func TestEnvSpaces(t *testing.T) {
+	env := NewEnv(newTestGame(t, DefaultConfig()))
+
+	if got := env.ActionSpace().N; got != 4 {
+		t.Errorf("ActionSpace().N = %d; want 4", got)
+	}
+	if got := env.ObservationSpace().Size(); got != len(env.Reset()) {
+		t.Errorf("ObservationSpace().Size() = %d; want %d", got, len(env.Reset()))
+	}
+}
+
+func TestLegalActions(t *testing.T) {
+	env := NewEnv(newTestGame(t, DefaultConfig()))
+
+	// Heading east, west would reverse the snake
+	if got, want := env.LegalActions(), []model.Action{0, 1, 2}; !slices.Equal(got, want) {
+		t.Errorf("LegalActions() heading east = %v; want %v", got, want)
+	}
+	env.Step(model.Action(2))
+	if got, want := env.LegalActions(), []model.Action{0, 2, 3}; !slices.Equal(got, want) {
+		t.Errorf("LegalActions() heading south = %v; want %v", got, want)
+	}
+
+	cfg := DefaultConfig()
+	cfg.RelativeActions = true
+	env = NewEnv(newTestGame(t, cfg))
+	if got, want := env.LegalActions(), []model.Action{TurnLeft, Straight, TurnRight}; !slices.Equal(got, want) {
+		t.Errorf("LegalActions() relative = %v; want %v", got, want)
+	}
+}
+
+func TestTurn(t *testing.T) {
+	testCases := []struct {
+		name   string
+		dir    model.Vector
+		action model.Action
+		want   model.Vector
+	}{
+		{"East, left", eastVector, TurnLeft, northVector},
+		{"East, straight", eastVector, Straight, eastVector},
+		{"East, right", eastVector, TurnRight, southVector},
+		{"North, left", northVector, TurnLeft, westVector},
+		{"North, right", northVector, TurnRight, eastVector},
+		{"West, left", westVector, TurnLeft, southVector},
+		{"West, right", westVector, TurnRight, northVector},
+		{"South, left", southVector, TurnLeft, eastVector},
+		{"South, right", southVector, TurnRight, westVector},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Turn(tc.dir, tc.action); got != tc.want {
+				t.Errorf("Turn(%v, %v) = %v; want %v", tc.dir, tc.action, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRelativeEnv(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Rows, cfg.Cols = 10, 10
+	cfg.RelativeActions = true
+	env := NewEnv(newTestGame(t, cfg))
+
+	if got := env.ActionSpace().N; got != 3 {
+		t.Errorf("ActionSpace().N = %d; want 3", got)
+	}
+
+	// Heading east from {0,3}: turning left runs into the top wall
+	if _, _, done, _ := env.Peek(TurnLeft); !done {
+		t.Errorf("Peek(TurnLeft) heading east on the top row didn't end the game")
+	}
+
+	// Turning right twice comes back along the row below
+	env.Step(TurnRight)
+	env.Step(TurnRight)
+	if got, want := env.game.CurrentLocation(), (model.Point{X: 1, Y: 2}); got != want {
+		t.Errorf("after two right turns head = %v; want %v", got, want)
+	}
+	if got := env.game.CurrentDirection(); got != westVector {
+		t.Errorf("after two right turns heading %v; want %v", got, westVector)
+	}
+}
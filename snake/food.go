@@ -0,0 +1,180 @@
+package snake
+
+import (
+	"fmt"
+
+	"github.com/casen/snakegame/model"
+)
+
+// FoodKind is what a food does to the snake that eats it
+type FoodKind int
+
+const (
+	NormalFood FoodKind = iota // scores and grows the snake
+	BonusFood                  // scores more, but doesn't stay on the board for long
+	PoisonFood                 // shrinks the snake, killing it when there's nothing left to lose
+	SpeedFood                  // scores, grows the snake and speeds the game up for a while
+	foodKinds
+)
+
+func (k FoodKind) String() string {
+	switch k {
+	case NormalFood:
+		return "normal"
+	case BonusFood:
+		return "bonus"
+	case PoisonFood:
+		return "poison"
+	case SpeedFood:
+		return "speed"
+	default:
+		return "unknown"
+	}
+}
+
+// Food is a food on the board
+type Food struct {
+	Kind FoodKind
+	At   model.Point
+	TTL  int // ticks left before it disappears, 0 for never
+}
+
+// FoodRule is how a kind of food behaves and when it turns up
+type FoodRule struct {
+	Kind   FoodKind
+	Points int // scored for eating it
+	TTL    int // ticks it stays on the board, 0 for as long as it takes
+	Shrink int // cells a poison takes off the snake
+	Boost  int // ticks a speed food speeds the game up for
+	Spawn  SpawnPolicy
+}
+
+// SpawnPolicy decides when a kind of food turns up. Min of it are kept on the
+// board, each replaced as soon as it's gone. Short of Max, another one turns
+// up with the given Chance every tick.
+type SpawnPolicy struct {
+	Min    int
+	Max    int
+	Chance float64
+}
+
+// FoodRules are the rules each kind of food plays by unless told otherwise.
+// Only the normal food is on the board by default, one at a time, like in the
+// classic game.
+var FoodRules = map[FoodKind]FoodRule{
+	NormalFood: {Kind: NormalFood, Points: 1, Spawn: SpawnPolicy{Min: 1, Max: 1}},
+	BonusFood:  {Kind: BonusFood, Points: 5, TTL: 30, Spawn: SpawnPolicy{Max: 1, Chance: 0.02}},
+	PoisonFood: {Kind: PoisonFood, Shrink: 2, TTL: 100, Spawn: SpawnPolicy{Max: 2, Chance: 0.02}},
+	SpeedFood:  {Kind: SpeedFood, Points: 1, TTL: 50, Boost: 30, Spawn: SpawnPolicy{Max: 1, Chance: 0.01}},
+}
+
+func (r FoodRule) validate() error {
+	if r.Kind < 0 || r.Kind >= foodKinds {
+		return fmt.Errorf("unknown food kind %d", r.Kind)
+	}
+	if r.Points < 0 || r.TTL < 0 || r.Shrink < 0 || r.Boost < 0 {
+		return fmt.Errorf("%v food can't have negative points, ttl, shrink or boost, got %+v", r.Kind, r)
+	}
+	if r.Spawn.Max < 1 || r.Spawn.Min < 0 || r.Spawn.Min > r.Spawn.Max {
+		return fmt.Errorf("%v food must spawn between 0 <= min <= max and max >= 1, got %+v", r.Kind, r.Spawn)
+	}
+	if r.Spawn.Chance < 0 || r.Spawn.Chance > 1 {
+		return fmt.Errorf("%v food spawn chance must be between 0 and 1, got %v", r.Kind, r.Spawn.Chance)
+	}
+	return nil
+}
+
+// Whether eating the food does the snake good
+func (f Food) edible() bool {
+	return f.Kind != PoisonFood
+}
+
+// The food at p, if there's one
+func (b *Board) foodAt(p model.Point) (int, bool) {
+	for i, f := range b.foods {
+		if f.At == p {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// The rule of the given kind of food
+func (b *Board) rule(kind FoodKind) FoodRule {
+	for _, r := range b.rules {
+		if r.Kind == kind {
+			return r
+		}
+	}
+	return FoodRules[kind]
+}
+
+// Ages the food on the board by a tick, then tops it up following the spawn
+// policy of each kind
+func (b *Board) spawnFood() {
+	foods := b.foods[:0]
+	for _, f := range b.foods {
+		if f.TTL > 0 {
+			if f.TTL--; f.TTL == 0 {
+				continue
+			}
+		}
+		foods = append(foods, f)
+	}
+	b.foods = foods
+
+	for _, r := range b.rules {
+		count := 0
+		for _, f := range b.foods {
+			if f.Kind == r.Kind {
+				count++
+			}
+		}
+		for ; count < r.Spawn.Min; count++ {
+			if !b.addFood(r) {
+				return
+			}
+		}
+		// No roll unless there's a chance, so boards without random food
+		// place it exactly where they always did
+		if count < r.Spawn.Max && r.Spawn.Chance > 0 && b.rng.Float64() < r.Spawn.Chance {
+			b.addFood(r)
+		}
+	}
+}
+
+// Puts a food of the rule's kind on the board, unless there's no room left
+func (b *Board) addFood(r FoodRule) bool {
+	at, ok := b.PlaceFood(r.Kind)
+	if !ok {
+		return false
+	}
+	b.foods = append(b.foods, Food{Kind: r.Kind, At: at, TTL: r.TTL})
+	return true
+}
+
+// The snake s eats the food at i, and whatever the food does is done to it.
+// Reports whether it was worth eating.
+func (b *Board) eat(s *Snake, i int) bool {
+	food := b.foods[i]
+	b.foods = append(b.foods[:i], b.foods[i+1:]...)
+	r := b.rule(food.Kind)
+
+	if food.Kind == PoisonFood {
+		if len(s.body)-r.Shrink < 2 {
+			s.cause = CausePoisoned
+			return false
+		}
+		s.body = s.body[r.Shrink:]
+		return false
+	}
+
+	// the snake grows over the next moves
+	s.growing += b.growth
+	s.hunger = 0
+	s.points += r.Points
+	if food.Kind == SpeedFood {
+		b.boost = r.Boost
+	}
+	return true
+}
@@ -0,0 +1,177 @@
+package snake
+
+import (
+	"testing"
+
+	"github.com/casen/snakegame/model"
+)
+
+// A board with a snake of 5 heading east along the second row and a food of
+// the given kind right in front of it, playing by the default rule of every
+// kind
+func newFoodBoard(kind FoodKind) *Board {
+	board := NewBoard(
+		10,
+		10,
+		NewSnake([]model.Point{{X: 1, Y: 0}, {X: 1, Y: 1}, {X: 1, Y: 2}, {X: 1, Y: 3}, {X: 1, Y: 4}}, eastVector),
+		model.Point{X: 8, Y: 8},
+	)
+	board.foods = append(board.foods, Food{Kind: kind, At: model.Point{X: 1, Y: 5}})
+	for _, k := range []FoodKind{BonusFood, PoisonFood, SpeedFood} {
+		board.rules = append(board.rules, FoodRules[k])
+	}
+	return board
+}
+
+func TestEatingFoods(t *testing.T) {
+	testCases := []struct {
+		kind       FoodKind
+		wantPoints int
+		wantLength int // once the snake has grown
+		wantReward float32
+		wantAte    bool
+	}{
+		{NormalFood, 1, 6, DefaultRewards.Food, true},
+		{BonusFood, 5, 6, 5 * DefaultRewards.Food, true},
+		{PoisonFood, 0, 3, DefaultRewards.Poison, false},
+		{SpeedFood, 1, 6, DefaultRewards.Food, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.kind.String(), func(t *testing.T) {
+			board := newFoodBoard(tc.kind)
+			result := board.Step(eastVector)
+			if result.Done || result.AteFood != tc.wantAte || result.Reward != tc.wantReward {
+				t.Errorf("Step() = %+v; want ate %t, reward %v", result, tc.wantAte, tc.wantReward)
+			}
+			board.Step(southVector)
+
+			if board.Score() != tc.wantPoints || board.Length() != tc.wantLength {
+				t.Errorf("points, length = %d, %d; want %d, %d", board.Score(), board.Length(), tc.wantPoints, tc.wantLength)
+			}
+			if want := tc.kind == SpeedFood; board.Boosted() != want {
+				t.Errorf("Boosted() = %t; want %t", board.Boosted(), want)
+			}
+		})
+	}
+}
+
+func TestPoisonKillsShortSnakes(t *testing.T) {
+	board := newFoodBoard(PoisonFood)
+	board.snake.body = board.snake.body[2:]
+
+	if !board.MoveIsTerminal(model.Point{X: 1, Y: 5}) {
+		t.Errorf("MoveIsTerminal() = false for a deadly poison; want true")
+	}
+	if board.MoveIsScoring(model.Point{X: 1, Y: 5}) {
+		t.Errorf("MoveIsScoring() = true for a poison; want false")
+	}
+	if result := board.Step(eastVector); result.Cause != CausePoisoned || result.Reward != DefaultRewards.Death {
+		t.Errorf("Step() = %+v; want %v, reward %v", result, CausePoisoned, DefaultRewards.Death)
+	}
+}
+
+func TestFoodSpawnPolicy(t *testing.T) {
+	cfg := DefaultConfig()
+	bonus := FoodRules[BonusFood]
+	bonus.TTL = 3
+	bonus.Spawn = SpawnPolicy{Max: 1, Chance: 1}
+	cfg.Foods = append(cfg.Foods, bonus)
+	game := newTestGame(t, cfg)
+
+	count := func(kind FoodKind) int {
+		n := 0
+		for _, f := range game.Foods() {
+			if f.Kind == kind {
+				n++
+			}
+		}
+		return n
+	}
+
+	if count(NormalFood) != 1 || count(BonusFood) != 0 {
+		t.Fatalf("Foods() = %v; want one normal food and no bonus yet", game.Foods())
+	}
+
+	// The bonus turns up on the first tick and stays for 3, then another one
+	// turns up straight away
+	var ttls []int
+	for i := 0; i < 5; i++ {
+		game.Step(model.Vector{})
+		for _, f := range game.Foods() {
+			if f.Kind == BonusFood {
+				ttls = append(ttls, f.TTL)
+			}
+		}
+	}
+	want := []int{3, 2, 1, 3, 2}
+	if len(ttls) != len(want) {
+		t.Fatalf("bonus TTLs = %v; want %v", ttls, want)
+	}
+	for i := range want {
+		if ttls[i] != want[i] {
+			t.Fatalf("bonus TTLs = %v; want %v", ttls, want)
+		}
+	}
+	if count(NormalFood) != 1 || count(BonusFood) != 1 {
+		t.Errorf("Foods() = %v; want one of each", game.Foods())
+	}
+}
+
+func TestFoodTheSnakeGoesFor(t *testing.T) {
+	board := newFoodBoard(PoisonFood)
+	board.foods = append(board.foods, Food{Kind: BonusFood, At: model.Point{X: 3, Y: 4}})
+
+	// The poison is closest, but the bonus is the nearest worth eating
+	if got := board.Food(); got != (model.Point{X: 3, Y: 4}) {
+		t.Errorf("Food() = %v; want %v", got, model.Point{X: 3, Y: 4})
+	}
+	if state := board.CurrentState(); state[10] != 1 || state[8] != 0 {
+		t.Errorf("CurrentState() = %v; want the food below", state)
+	}
+}
+
+func TestSpeedFoodHalvesTheInterval(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Foods = []FoodRule{FoodRules[NormalFood], FoodRules[SpeedFood]}
+	game := newTestGame(t, cfg)
+	before := game.Interval()
+
+	game.board.boost = 1
+	if got := game.Interval(); got != before/2 {
+		t.Errorf("Interval() boosted = %v; want %v", got, before/2)
+	}
+	game.Step(model.Vector{})
+	if got := game.Interval(); got != before {
+		t.Errorf("Interval() once the boost wears off = %v; want %v", got, before)
+	}
+}
+
+func TestPlaceFoodOnAFullBoard(t *testing.T) {
+	board := newFoodBoard(NormalFood)
+	last := model.Point{X: 9, Y: 9}
+	board.obstacles = map[model.Point]bool{}
+	for x := 0; x < board.rows; x++ {
+		for y := 0; y < board.cols; y++ {
+			if p := (model.Point{X: x, Y: y}); p != last && board.free(p) {
+				board.obstacles[p] = true
+			}
+		}
+	}
+
+	for _, kind := range []FoodKind{NormalFood, BonusFood} {
+		if at, ok := board.PlaceFood(kind); !ok || at != last {
+			t.Errorf("PlaceFood(%v) = %v, %t; want the last free cell %v", kind, at, ok, last)
+		}
+	}
+
+	board.obstacles[last] = true
+	for _, kind := range []FoodKind{NormalFood, BonusFood} {
+		if at, ok := board.PlaceFood(kind); ok {
+			t.Errorf("PlaceFood(%v) = %v on a full board; want no cell", kind, at)
+		}
+	}
+	if board.addFood(FoodRules[BonusFood]) {
+		t.Errorf("addFood() = true on a full board; want false")
+	}
+}
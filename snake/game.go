@@ -1,70 +1,156 @@
 package snake
 
 import (
-	"fmt"
-	"image/color"
+	"math/rand"
+	"time"
 
 	"github.com/casen/snakegame/model"
-	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
-	"github.com/hajimehoshi/ebiten/v2/vector"
 )
 
+// The largest the board is ever drawn, the cells are sized to fit inside
 const (
 	ScreenWidth  = 600
 	ScreenHeight = 600
-	boardRows    = 20
-	boardCols    = 20
-)
-
-var (
-	backgroundColor = color.RGBA{50, 100, 50, 50}
-	snakeColor      = color.RGBA{0, 255, 0, 255}
-	foodColor       = color.RGBA{200, 200, 50, 150}
 )
 
 type Game struct {
-	board *Board
+	board  *Board
+	config Config
+
+	// Every episode places its food with an rng of its own, seeded by seed.
+	// The first is seeded by the config, the rest by seeds.
+	seeds *rand.Rand
+	seed  int64
+
+	recorder *Recorder
+	ticks    int // steps taken in the episode being played
+}
+
+// Creates a game played by the rules in cfg. Food placement is driven by
+// cfg.Seed, and each reset seeds the next episode from it, so a whole
+// sequence of games is reproducible from one seed, and each game on its own
+// from the seed of the episode, see Seed.
+func NewGame(cfg Config) (*Game, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	g := &Game{
+		config: cfg,
+		seeds:  rand.New(rand.NewSource(cfg.Seed)),
+	}
+	g.start(cfg.Seed)
+	return g, nil
 }
 
-func NewGame() *Game {
-	return &Game{
-		board: NewGameBoard(boardRows, boardCols),
+// Sets up a new episode seeded by seed
+func (g *Game) start(seed int64) {
+	g.seed = seed
+	g.ticks = 0
+	g.board = NewGameBoard(g.config, rand.New(rand.NewSource(seed)))
+	if g.recorder != nil {
+		g.recorder.episode(g.config, seed)
 	}
 }
 
-func (g *Game) Update(action model.Vector) error {
-	return g.board.Update(action)
+// Step advances the game by exactly one tick, see Board.Step
+func (g *Game) Step(action model.Vector) StepResult {
+	actions := make([]model.Vector, g.Players())
+	actions[0] = action
+	return g.StepAll(actions)[0]
 }
 
-func (g *Game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
-	return ScreenWidth, ScreenHeight
+// StepAll advances the game by exactly one tick, with one action for each
+// player, see Board.StepAll
+func (g *Game) StepAll(actions []model.Vector) []StepResult {
+	results := g.board.StepAll(actions)
+	g.ticks++
+	if g.recorder != nil {
+		g.recorder.tick(actions, results)
+	}
+	return results
 }
 
-func (g *Game) Draw(screen *ebiten.Image) {
-	screen.Fill(backgroundColor)
-	if g.board.gameOver {
-		ebitenutil.DebugPrint(screen, fmt.Sprintf("Game Over. Score: %d", g.board.points))
-	} else {
-		width := ScreenHeight / boardRows
+// Seed is the seed the food of the episode being played is placed with, see
+// Replayer
+func (g *Game) Seed() int64 {
+	return g.seed
+}
 
-		for _, p := range g.board.snake.body {
-			vector.DrawFilledRect(screen, float32(p.Y*width), float32(p.X*width), float32(width), float32(width), snakeColor, true)
-		}
-		vector.DrawFilledRect(screen, float32(g.board.food.Y*width), float32(g.board.food.X*width), float32(width), float32(width), foodColor, true)
-		ebitenutil.DebugPrint(screen, fmt.Sprintf("Score: %d", g.board.points))
+// Record has r write down every episode from the one being played, when it
+// has yet to take its first step, or else from the next one on, see Recorder
+func (g *Game) Record(r *Recorder) {
+	g.recorder = r
+	if r != nil && g.ticks == 0 {
+		r.episode(g.config, g.seed)
 	}
 }
+
+// Players is how many snakes play the game, player 0 being the snake of the
+// config and the rest its rivals
+func (g *Game) Players() int {
+	return g.board.Snakes()
+}
+
+// ScoreOf is what the given player has scored
+func (g *Game) ScoreOf(player int) int {
+	return g.board.snakes[player].points
+}
+
+// Alive reports whether the given player is still in the game
+func (g *Game) Alive(player int) bool {
+	return g.board.snakes[player].alive()
+}
+
+// CauseOf reports why the given player died, or CauseNone while it's alive
+func (g *Game) CauseOf(player int) Cause {
+	return g.board.snakes[player].cause
+}
+
+// Interval is how long a frontend should wait between steps. The snake speeds
+// up as the score grows, following the speed curve of the config, and goes
+// twice as fast for a while after eating a speed food.
+func (g *Game) Interval() time.Duration {
+	interval := g.config.interval(g.board.Score())
+	if g.board.Boosted() {
+		interval /= 2
+	}
+	return interval
+}
+
+// Config returns the rules the game is played by
+func (g *Game) Config() Config {
+	return g.config
+}
+
+// The size in pixels of a single cell when the board is drawn, so that the
+// whole board fits the screen
+func (g *Game) cellSize() int {
+	return min(ScreenWidth/g.config.Cols, ScreenHeight/g.config.Rows)
+}
+
 func (g *Game) GameOver() bool {
 	return g.board.GameOver()
 }
 
+// Cause reports why the game ended for player 0, or CauseNone while it is
+// still running
+func (g *Game) Cause() Cause {
+	return g.board.Cause()
+}
+
+// Hash is the Zobrist hash of the board as it is now
+func (g *Game) Hash() uint64 {
+	return g.board.Hash()
+}
+
 func (g *Game) Reset() {
-	g.board = NewGameBoard(boardRows, boardCols)
+	g.start(g.seeds.Int63())
 }
 
+// Score is what player 0 has scored
 func (g *Game) Score() int {
-	return g.board.points
+	return g.board.Score()
 }
 
 func (g *Game) CurrentDirection() model.Vector {
@@ -75,8 +161,14 @@ func (g *Game) CurrentLocation() model.Point {
 	return g.board.snake.Head()
 }
 
+// FoodLocation is where the food the snake should go for is, see Board.Food
 func (g *Game) FoodLocation() model.Point {
-	return g.board.food
+	return g.board.Food()
+}
+
+// Foods lists the food on the board
+func (g *Game) Foods() []Food {
+	return g.board.Foods()
 }
 
 func (g *Game) PrintGameBoard() {
@@ -89,10 +181,6 @@ func (g *Game) MoveIsValid(dir model.Vector) bool {
 	return isValid
 }
 
-func (g *Game) Move(dir model.Vector) {
-	g.board.Move(dir)
-}
-
 func (g *Game) EvaluateAction(action model.Vector) (reward float32, isDone bool) {
 	return g.board.EvaluateAction(action)
 }
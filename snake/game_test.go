@@ -0,0 +1,215 @@
+package snake
+
+import (
+	"testing"
+
+	"github.com/casen/snakegame/model"
+)
+
+func TestStep(t *testing.T) {
+	type stepCase struct {
+		name        string
+		board       *Board
+		action      model.Vector
+		wantHead    model.Point
+		wantResult  StepResult
+		wantGrowing int
+	}
+
+	newTestBoard := func(body []model.Point, dir model.Vector, food model.Point) *Board {
+		return NewBoard(10, 10, NewSnake(body, dir), food)
+	}
+	alongTop := []model.Point{{X: 0, Y: 0}, {X: 0, Y: 1}, {X: 0, Y: 2}, {X: 0, Y: 3}}
+
+	testCases := []stepCase{
+		{
+			"Keep going east",
+			newTestBoard(alongTop, eastVector, model.Point{X: 5, Y: 5}),
+			eastVector, model.Point{X: 0, Y: 4},
+			StepResult{Reward: 2}, 0,
+		},
+		{
+			"No action keeps the current direction",
+			newTestBoard(alongTop, eastVector, model.Point{X: 5, Y: 5}),
+			model.Vector{}, model.Point{X: 0, Y: 4},
+			StepResult{Reward: 2}, 0,
+		},
+		{
+			"Reversing is ignored",
+			newTestBoard(alongTop, eastVector, model.Point{X: 5, Y: 5}),
+			westVector, model.Point{X: 0, Y: 4},
+			StepResult{Reward: 2}, 0,
+		},
+		{
+			"Eat the food",
+			newTestBoard(alongTop, eastVector, model.Point{X: 0, Y: 4}),
+			eastVector, model.Point{X: 0, Y: 4},
+			StepResult{Reward: 100, AteFood: true}, 1,
+		},
+		{
+			"Run into the wall",
+			newTestBoard(alongTop, eastVector, model.Point{X: 5, Y: 5}),
+			northVector, model.Point{X: -1, Y: 3},
+			StepResult{Reward: -100, Done: true, Cause: CauseWall}, 0,
+		},
+		{
+			"Run into the body",
+			newTestBoard([]model.Point{{X: 1, Y: 1}, {X: 1, Y: 2}, {X: 1, Y: 3}, {X: 2, Y: 3}, {X: 2, Y: 2}}, westVector, model.Point{X: 5, Y: 5}),
+			northVector, model.Point{X: 1, Y: 2},
+			StepResult{Reward: -100, Done: true, Cause: CauseSelf}, 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.board.Step(tc.action)
+			if got != tc.wantResult {
+				t.Errorf("Step() = %+v; want %+v", got, tc.wantResult)
+			}
+
+			if head := tc.board.snake.Head(); head != tc.wantHead {
+				t.Errorf("Step() moved head to %v; want %v", head, tc.wantHead)
+			}
+
+			if tc.board.snake.growing != tc.wantGrowing {
+				t.Errorf("Step() left snake growing %d; want %d", tc.board.snake.growing, tc.wantGrowing)
+			}
+		})
+	}
+}
+
+func TestStepAfterGameOver(t *testing.T) {
+	board := NewBoard(
+		10,
+		10,
+		NewSnake([]model.Point{{X: 0, Y: 0}, {X: 0, Y: 1}, {X: 0, Y: 2}, {X: 0, Y: 3}}, eastVector),
+		model.Point{X: 5, Y: 5},
+	)
+
+	board.Step(northVector)
+	head := board.snake.Head()
+
+	got := board.Step(eastVector)
+	want := StepResult{Done: true, Cause: CauseWall}
+	if got != want {
+		t.Errorf("Step() = %+v; want %+v", got, want)
+	}
+
+	if board.snake.Head() != head {
+		t.Errorf("Step() moved the snake after game over to %v; want %v", board.snake.Head(), head)
+	}
+}
+
+func newTestGame(t *testing.T, cfg Config) *Game {
+	t.Helper()
+	game, err := NewGame(cfg)
+	if err != nil {
+		t.Fatalf("NewGame() = %v; want nil", err)
+	}
+	return game
+}
+
+func TestSameSeedSameFood(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Seed = 42
+	a, b := newTestGame(t, cfg), newTestGame(t, cfg)
+
+	for i := 0; i < 5; i++ {
+		if a.FoodLocation() != b.FoodLocation() {
+			t.Fatalf("game %d: FoodLocation() = %v and %v; want the same", i, a.FoodLocation(), b.FoodLocation())
+		}
+		a.Reset()
+		b.Reset()
+	}
+}
+
+func TestStarvation(t *testing.T) {
+	testCases := []struct {
+		name      string
+		rule      StarveRule
+		wantMoves int // moves until the snake starves, 0 if it never does
+	}{
+		{"Off", StarveRule{}, 0},
+		{"Flat limit", StarveRule{Limit: 3}, 4},
+		{"Scaled by length", StarveRule{Limit: 3, PerCell: 1}, 8},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// A snake of 4 circling a 2x2 square in the middle of the board,
+			// nowhere near the food
+			board := NewBoard(
+				10,
+				10,
+				NewSnake([]model.Point{{X: 4, Y: 4}, {X: 4, Y: 5}, {X: 5, Y: 5}, {X: 5, Y: 4}}, westVector),
+				model.Point{X: 0, Y: 0},
+			)
+			board.starve = tc.rule
+			circle := []model.Vector{northVector, eastVector, southVector, westVector}
+
+			for move := 1; move <= 20; move++ {
+				result := board.Step(circle[(move-1)%4])
+				if !result.Done {
+					continue
+				}
+
+				if move != tc.wantMoves {
+					t.Fatalf("starved after %d moves; want %d", move, tc.wantMoves)
+				}
+				if result.Cause != CauseStarved || result.Reward != DefaultRewards.Death {
+					t.Errorf("Step() = %+v; want starved with the death reward", result)
+				}
+				return
+			}
+
+			if tc.wantMoves != 0 {
+				t.Errorf("still alive after 20 moves; want starved after %d", tc.wantMoves)
+			}
+		})
+	}
+}
+
+func TestMaxMoves(t *testing.T) {
+	// A snake of 4 circling a 2x2 square, which would never starve
+	board := NewBoard(
+		10,
+		10,
+		NewSnake([]model.Point{{X: 4, Y: 4}, {X: 4, Y: 5}, {X: 5, Y: 5}, {X: 5, Y: 4}}, westVector),
+		model.Point{X: 0, Y: 0},
+	)
+	board.maxMoves = 6
+	circle := []model.Vector{northVector, eastVector, southVector, westVector}
+
+	for move := 1; move <= 20; move++ {
+		if result := board.Step(circle[(move-1)%4]); result.Done {
+			if move != 6 || result.Cause != CauseTimedOut {
+				t.Errorf("Step() = %+v after %d moves; want timed out after 6", result, move)
+			}
+			return
+		}
+	}
+	t.Errorf("still going after 20 moves; want timed out after 6")
+}
+
+func TestEndLoops(t *testing.T) {
+	// The same circling snake, which is back where it started four moves on
+	board := NewBoard(
+		10,
+		10,
+		NewSnake([]model.Point{{X: 4, Y: 4}, {X: 4, Y: 5}, {X: 5, Y: 5}, {X: 5, Y: 4}}, westVector),
+		model.Point{X: 0, Y: 0},
+	)
+	board.loops = NewCycleDetector()
+	board.loops.Observe(board.Hash())
+	circle := []model.Vector{northVector, eastVector, southVector, westVector}
+
+	for move := 1; move <= 20; move++ {
+		if result := board.Step(circle[(move-1)%4]); result.Done {
+			if move != 4 || result.Cause != CauseLooped {
+				t.Errorf("Step() = %+v after %d moves; want looped after 4", result, move)
+			}
+			return
+		}
+	}
+	t.Errorf("still going after 20 moves; want looped after 4")
+}
@@ -1,36 +1,57 @@
+//go:build !headless
+
 package snake
 
 import (
 	"log"
 
-	. "github.com/casen/snakegame/model"
+	"github.com/casen/snakegame/model"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 )
 
-type Input struct{}
+// Keys are the keys a player steers their snake with
+type Keys struct {
+	Up    ebiten.Key
+	Down  ebiten.Key
+	Left  ebiten.Key
+	Right ebiten.Key
+}
+
+// The two players sharing a keyboard
+var (
+	ArrowKeys = Keys{Up: ebiten.KeyArrowUp, Down: ebiten.KeyArrowDown, Left: ebiten.KeyArrowLeft, Right: ebiten.KeyArrowRight}
+	WASDKeys  = Keys{Up: ebiten.KeyW, Down: ebiten.KeyS, Left: ebiten.KeyA, Right: ebiten.KeyD}
+)
+
+type Input struct {
+	keys Keys
+}
 
+// NewInput steers with the arrow keys
 func NewInput() *Input {
-	return &Input{}
+	return NewInputWithKeys(ArrowKeys)
 }
 
-func (i *Input) Action() (ebiten.Key, Vector, bool) {
-	if inpututil.IsKeyJustPressed(ebiten.KeyArrowUp) {
-		log.Printf("pressed up")
-		return ebiten.KeyArrowUp, Vector{X: -1, Y: 0}, true
-	}
-	if inpututil.IsKeyJustPressed(ebiten.KeyArrowLeft) {
-		log.Printf("pressed left")
-		return ebiten.KeyArrowLeft, Vector{X: 0, Y: -1}, true
-	}
-	if inpututil.IsKeyJustPressed(ebiten.KeyArrowRight) {
-		log.Printf("pressed left")
-		return ebiten.KeyArrowRight, Vector{X: 0, Y: 1}, true
-	}
-	if inpututil.IsKeyJustPressed(ebiten.KeyArrowDown) {
-		log.Printf("pressed down")
-		return ebiten.KeyArrowDown, Vector{X: 1, Y: 0}, true
+func NewInputWithKeys(keys Keys) *Input {
+	return &Input{keys: keys}
+}
+
+func (i *Input) Action() (ebiten.Key, model.Vector, bool) {
+	for _, k := range []struct {
+		key ebiten.Key
+		dir model.Vector
+	}{
+		{i.keys.Up, model.Vector{X: -1, Y: 0}},
+		{i.keys.Left, model.Vector{X: 0, Y: -1}},
+		{i.keys.Right, model.Vector{X: 0, Y: 1}},
+		{i.keys.Down, model.Vector{X: 1, Y: 0}},
+	} {
+		if inpututil.IsKeyJustPressed(k.key) {
+			log.Printf("pressed %v", k.key)
+			return k.key, k.dir, true
+		}
 	}
 
-	return 0, Vector{X: 0, Y: 0}, false
+	return 0, model.Vector{X: 0, Y: 0}, false
 }
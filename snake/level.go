@@ -0,0 +1,324 @@
+package snake
+
+import (
+	"bufio"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/casen/snakegame/model"
+)
+
+// Level is a board to play on, with obstacles where the edges aren't the only
+// walls. Levels are written as text: a few headers, then the board as a grid
+// of cells, one line per row.
+//
+//	name: Pillars
+//	direction: east
+//	..........
+//	.SSS......
+//	....#..F..
+//	..........
+//
+// A '#' is an obstacle, an 'S' the starting snake, an 'F' a fixed spot for
+// food and a '.' an empty cell. The snake is a line of S cells that touch only
+// along the snake, and its head is the end it heads away from. When either end
+// could be the head, a "head: row,col" header says which.
+type Level struct {
+	Name string
+	Rows int
+	Cols int
+
+	// The starting body of the snake from tail to head, and the direction it
+	// sets off in
+	Snake     []model.Point
+	Direction model.Vector
+
+	Obstacles []model.Point
+
+	// Where the normal food turns up, when it doesn't turn up just anywhere
+	Food []model.Point
+}
+
+// The cells of the grid of a level
+const (
+	cellEmpty    = '.'
+	cellObstacle = '#'
+	cellSnake    = 'S'
+	cellFood     = 'F'
+)
+
+var directionNames = map[string]model.Vector{
+	"east":  Directions[0],
+	"north": Directions[1],
+	"south": Directions[2],
+	"west":  Directions[3],
+}
+
+func directionName(dir model.Vector) string {
+	for name, d := range directionNames {
+		if d == dir {
+			return name
+		}
+	}
+	return fmt.Sprint(dir)
+}
+
+// ParseLevel reads a level written as text
+func ParseLevel(r io.Reader) (*Level, error) {
+	l := &Level{}
+	var head *model.Point
+	var snake []model.Point
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		if text == "" {
+			continue
+		}
+
+		// Headers come before the grid
+		if key, value, ok := strings.Cut(text, ":"); ok && l.Rows == 0 {
+			value = strings.TrimSpace(value)
+			switch strings.TrimSpace(key) {
+			case "name":
+				l.Name = value
+			case "direction":
+				dir, ok := directionNames[strings.ToLower(value)]
+				if !ok {
+					return nil, fmt.Errorf("line %d: unknown direction %q", line, value)
+				}
+				l.Direction = dir
+			case "head":
+				p, err := parsePoint(value)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", line, err)
+				}
+				head = &p
+			default:
+				return nil, fmt.Errorf("line %d: unknown header %q", line, key)
+			}
+			continue
+		}
+
+		if l.Cols == 0 {
+			l.Cols = len(text)
+		}
+		if len(text) != l.Cols {
+			return nil, fmt.Errorf("line %d: row is %d cells wide, the rows above are %d", line, len(text), l.Cols)
+		}
+
+		row := l.Rows
+		for col, c := range []byte(text) {
+			p := model.Point{X: row, Y: col}
+			switch c {
+			case cellEmpty:
+			case cellObstacle:
+				l.Obstacles = append(l.Obstacles, p)
+			case cellSnake:
+				snake = append(snake, p)
+			case cellFood:
+				l.Food = append(l.Food, p)
+			default:
+				return nil, fmt.Errorf("line %d: unknown cell %q", line, c)
+			}
+		}
+		l.Rows++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if l.Rows == 0 {
+		return nil, errors.New("level has no grid")
+	}
+	if l.Direction == (model.Vector{}) {
+		return nil, errors.New("level needs a direction for the snake")
+	}
+
+	var err error
+	if l.Snake, err = orderSnake(snake, l.Direction, head); err != nil {
+		return nil, err
+	}
+
+	cfg := DefaultConfig()
+	l.Apply(&cfg)
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func parsePoint(s string) (model.Point, error) {
+	row, col, ok := strings.Cut(s, ",")
+	x, errX := strconv.Atoi(strings.TrimSpace(row))
+	y, errY := strconv.Atoi(strings.TrimSpace(col))
+	if !ok || errX != nil || errY != nil {
+		return model.Point{}, fmt.Errorf("%q is not a row,col", s)
+	}
+	return model.Point{X: x, Y: y}, nil
+}
+
+// Strings the S cells of a level together from tail to head
+func orderSnake(cells []model.Point, dir model.Vector, head *model.Point) ([]model.Point, error) {
+	if len(cells) < 2 {
+		return nil, fmt.Errorf("snake must be at least 2 long, got %d", len(cells))
+	}
+
+	isSnake := make(map[model.Point]bool, len(cells))
+	for _, p := range cells {
+		isSnake[p] = true
+	}
+	neighbours := func(p model.Point) []model.Point {
+		var out []model.Point
+		for _, d := range Directions {
+			if q := (model.Point{X: p.X + d.X, Y: p.Y + d.Y}); isSnake[q] {
+				out = append(out, q)
+			}
+		}
+		return out
+	}
+
+	var ends []model.Point
+	for _, p := range cells {
+		switch len(neighbours(p)) {
+		case 1:
+			ends = append(ends, p)
+		case 2:
+		default:
+			return nil, fmt.Errorf("snake touches itself at %v", p)
+		}
+	}
+	if len(ends) != 2 {
+		return nil, errors.New("snake must be a single line of cells")
+	}
+
+	// The head is an end the snake can head away from without running into
+	// its neck
+	var heads []model.Point
+	for _, p := range ends {
+		neck := neighbours(p)[0]
+		if (model.Point{X: p.X + dir.X, Y: p.Y + dir.Y}) != neck {
+			heads = append(heads, p)
+		}
+	}
+	switch {
+	case head != nil:
+		if *head != ends[0] && *head != ends[1] {
+			return nil, fmt.Errorf("head %v is not an end of the snake", *head)
+		}
+		heads = []model.Point{*head}
+	case len(heads) != 1:
+		return nil, errors.New("either end of the snake could be its head, say which with a head header")
+	}
+
+	// Walk from the head back to the tail
+	body := []model.Point{heads[0]}
+	seen := map[model.Point]bool{heads[0]: true}
+	for len(body) < len(cells) {
+		next, ok := model.Point{}, false
+		for _, q := range neighbours(body[len(body)-1]) {
+			if !seen[q] {
+				next, ok = q, true
+			}
+		}
+		if !ok {
+			return nil, errors.New("snake must be a single line of cells")
+		}
+		seen[next] = true
+		body = append(body, next)
+	}
+	for i, j := 0, len(body)-1; i < j; i, j = i+1, j-1 {
+		body[i], body[j] = body[j], body[i]
+	}
+
+	return body, nil
+}
+
+// WriteTo writes the level as text, the way ParseLevel reads it
+func (l *Level) WriteTo(w io.Writer) (int64, error) {
+	var sb strings.Builder
+
+	if l.Name != "" {
+		fmt.Fprintf(&sb, "name: %s\n", l.Name)
+	}
+	fmt.Fprintf(&sb, "direction: %s\n", directionName(l.Direction))
+	if len(l.Snake) >= 2 {
+		head := l.Snake[len(l.Snake)-1]
+		tail := l.Snake[0]
+		neck := l.Snake[1]
+		if (model.Point{X: tail.X + l.Direction.X, Y: tail.Y + l.Direction.Y}) != neck {
+			// Read back, the tail could pass for the head
+			fmt.Fprintf(&sb, "head: %d,%d\n", head.X, head.Y)
+		}
+	}
+
+	grid := make([][]byte, l.Rows)
+	for i := range grid {
+		grid[i] = []byte(strings.Repeat(string(cellEmpty), l.Cols))
+	}
+	mark := func(points []model.Point, c byte) {
+		for _, p := range points {
+			grid[p.X][p.Y] = c
+		}
+	}
+	mark(l.Food, cellFood)
+	mark(l.Obstacles, cellObstacle)
+	mark(l.Snake, cellSnake)
+	for _, row := range grid {
+		sb.Write(row)
+		sb.WriteByte('\n')
+	}
+
+	n, err := io.WriteString(w, sb.String())
+	return int64(n), err
+}
+
+// Apply sets up cfg to play the level
+func (l *Level) Apply(cfg *Config) {
+	cfg.Rows = l.Rows
+	cfg.Cols = l.Cols
+	cfg.Snake = append([]model.Point(nil), l.Snake...)
+	cfg.Direction = l.Direction
+	cfg.Obstacles = append([]model.Point(nil), l.Obstacles...)
+	cfg.FoodSpots = append([]model.Point(nil), l.Food...)
+}
+
+//go:embed levels/*.txt
+var builtinLevels embed.FS
+
+// Levels lists the names of the built-in levels
+func Levels() []string {
+	entries, _ := builtinLevels.ReadDir("levels")
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".txt"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadLevel reads the built-in level of the given name, or failing that the
+// level file at the given path
+func LoadLevel(nameOrPath string) (*Level, error) {
+	f, err := builtinLevels.Open(path.Join("levels", nameOrPath+".txt"))
+	if err != nil {
+		if f, err = os.Open(nameOrPath); err != nil {
+			return nil, err
+		}
+	}
+	defer f.Close()
+
+	l, err := ParseLevel(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", nameOrPath, err)
+	}
+	return l, nil
+}
@@ -0,0 +1,174 @@
+package snake
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/casen/snakegame/model"
+)
+
+func TestParseLevel(t *testing.T) {
+	text := `name: Test
+direction: north
+.....
+.#.F.
+.#S..
+..S..
+.....
+`
+	l, err := ParseLevel(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("ParseLevel() = %v", err)
+	}
+
+	if l.Name != "Test" || l.Rows != 5 || l.Cols != 5 {
+		t.Errorf("ParseLevel() = %q %dx%d; want \"Test\" 5x5", l.Name, l.Rows, l.Cols)
+	}
+	wantSnake := []model.Point{{X: 3, Y: 2}, {X: 2, Y: 2}}
+	if len(l.Snake) != 2 || l.Snake[0] != wantSnake[0] || l.Snake[1] != wantSnake[1] {
+		t.Errorf("Snake = %v; want %v, tail to head", l.Snake, wantSnake)
+	}
+	if l.Direction != northVector {
+		t.Errorf("Direction = %v; want %v", l.Direction, northVector)
+	}
+	if len(l.Obstacles) != 2 || len(l.Food) != 1 || l.Food[0] != (model.Point{X: 1, Y: 3}) {
+		t.Errorf("Obstacles, Food = %v, %v; want 2 obstacles and food at {1 3}", l.Obstacles, l.Food)
+	}
+}
+
+func TestParseLevelErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		text string
+	}{
+		{"No direction", "SS..\n....\n"},
+		{"Unknown direction", "direction: up\nSS..\n....\n"},
+		{"Unknown header", "speed: fast\ndirection: east\nSS..\n....\n"},
+		{"Unknown cell", "direction: east\nSS.x\n....\n"},
+		{"Ragged rows", "direction: east\nSS..\n...\n"},
+		{"No snake", "direction: east\n....\n....\n"},
+		{"Snake in two", "direction: east\nSS..\n..SS\n"},
+		{"Snake touching itself", "direction: east\nSS..\nSS..\n"},
+		{"Snake on an obstacle", "direction: east\n#...\n....\n"},
+		{"Either end the head", "direction: south\nSS..\n.S..\n....\n"},
+		{"Head in the middle", "direction: north\nhead: 0,1\n.S..\nSSS.\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ParseLevel(strings.NewReader(tc.text)); err == nil {
+				t.Errorf("ParseLevel() = nil; want an error")
+			}
+		})
+	}
+}
+
+func TestLevelRoundTrip(t *testing.T) {
+	// Either end of this snake could head north, so the head is written down
+	text := `direction: north
+head: 0,2
+S.S.
+SSS#
+..F.
+`
+	l, err := ParseLevel(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("ParseLevel() = %v", err)
+	}
+	if got := l.Snake[len(l.Snake)-1]; got != (model.Point{X: 0, Y: 2}) {
+		t.Errorf("head = %v; want {0 2}", got)
+	}
+
+	var sb strings.Builder
+	if _, err := l.WriteTo(&sb); err != nil {
+		t.Fatalf("WriteTo() = %v", err)
+	}
+	if sb.String() != text {
+		t.Errorf("WriteTo() = %q; want %q", sb.String(), text)
+	}
+}
+
+func TestBuiltinLevels(t *testing.T) {
+	names := Levels()
+	if len(names) < 3 {
+		t.Errorf("Levels() = %v; want several", names)
+	}
+
+	for _, name := range names {
+		l, err := LoadLevel(name)
+		if err != nil {
+			t.Errorf("LoadLevel(%q) = %v", name, err)
+			continue
+		}
+
+		cfg := DefaultConfig()
+		l.Apply(&cfg)
+		newTestGame(t, cfg)
+	}
+}
+
+func TestObstacles(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Rows, cfg.Cols = 5, 5
+	cfg.Snake = []model.Point{{X: 0, Y: 0}, {X: 0, Y: 1}}
+	cfg.Direction = eastVector
+
+	// Every free cell but one is an obstacle, so that's where the food goes
+	for x := 1; x < cfg.Rows; x++ {
+		for y := 0; y < cfg.Cols; y++ {
+			if x != 4 || y != 4 {
+				cfg.Obstacles = append(cfg.Obstacles, model.Point{X: x, Y: y})
+			}
+		}
+	}
+	game := newTestGame(t, cfg)
+	for i := 0; i < 10; i++ {
+		game.Reset()
+		if food := game.FoodLocation(); game.board.Obstacle(food) {
+			t.Fatalf("food placed on the obstacle at %v", food)
+		}
+	}
+
+	if !game.board.MoveIsTerminal(model.Point{X: 1, Y: 1}) {
+		t.Errorf("MoveIsTerminal() = false for an obstacle; want true")
+	}
+	if !game.board.DangerRight() {
+		t.Errorf("DangerRight() = false with an obstacle to the right; want true")
+	}
+
+	if result := game.Step(southVector); result.Cause != CauseObstacle {
+		t.Errorf("Step() into an obstacle = %+v; want %v", result, CauseObstacle)
+	}
+}
+
+func TestFixedFood(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.FoodSpots = []model.Point{{X: 0, Y: 6}, {X: 0, Y: 12}}
+	game := newTestGame(t, cfg)
+
+	// Heading east along the top row, eating the food as it comes
+	for _, want := range []model.Point{{X: 0, Y: 6}, {X: 0, Y: 12}, {X: 0, Y: 6}} {
+		if got := game.FoodLocation(); got != want {
+			t.Fatalf("food at %v; want %v", got, want)
+		}
+		for game.FoodLocation() == want && !game.GameOver() {
+			game.Step(eastVector)
+		}
+	}
+}
+
+func TestBoardLevel(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Obstacles = []model.Point{{X: 5, Y: 5}}
+	game := newTestGame(t, cfg)
+
+	var sb strings.Builder
+	game.board.Level().WriteTo(&sb)
+	l, err := ParseLevel(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatalf("ParseLevel() of the board written down = %v\n%s", err, sb.String())
+	}
+	if l.Rows != 20 || l.Cols != 20 || len(l.Snake) != 4 || len(l.Obstacles) != 1 || l.Food[0] != game.FoodLocation() {
+		t.Errorf("board written down and read back = %+v\n%s", l, sb.String())
+	}
+}
@@ -0,0 +1,121 @@
+package snake
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+
+	"github.com/casen/snakegame/model"
+)
+
+// Position is a board in the middle of a game, for games played somewhere
+// else and only looked at here, see Game.Load
+type Position struct {
+	Rows  int
+	Cols  int
+	Walls WallMode
+
+	// The first is player 0, the one the game is seen from
+	Snakes    []SnakePosition
+	Foods     []Food
+	Obstacles []model.Point
+}
+
+// SnakePosition is a snake in the middle of a game
+type SnakePosition struct {
+	Body      []model.Point // tail to head, a single cell for a snake all coiled up
+	Direction model.Vector  // the zero vector for a snake that hasn't moved yet
+	Growing   int           // cells it still has to grow
+	Hunger    int           // moves since it last ate
+	Points    int
+	Cause     Cause // why it died, CauseNone while it's alive
+}
+
+// Load sets the game to p, to be played on from there by the rules of the
+// config. The starting snakes and the board size of the config are left as
+// they are, so Reset goes back to the game the config describes. Positions
+// aren't recorded, see Record.
+func (g *Game) Load(p Position) error {
+	if p.Rows < 2 || p.Cols < 2 {
+		return fmt.Errorf("board must be at least 2x2, got %dx%d", p.Rows, p.Cols)
+	}
+	if len(p.Snakes) == 0 {
+		return errors.New("position needs at least one snake")
+	}
+
+	cfg := g.config
+	cfg.Rows, cfg.Cols = p.Rows, p.Cols
+	cfg.Walls = p.Walls
+	cfg.Obstacles = p.Obstacles
+	cfg.FoodSpots = nil
+
+	onBoard := func(what string, points ...model.Point) error {
+		for _, q := range points {
+			if !cfg.onBoard(q) {
+				return fmt.Errorf("%s %v is off the %dx%d board", what, q, p.Rows, p.Cols)
+			}
+		}
+		return nil
+	}
+	if err := onBoard("obstacle", p.Obstacles...); err != nil {
+		return err
+	}
+
+	snakes := make([]*Snake, len(p.Snakes))
+	for i, sp := range p.Snakes {
+		if len(sp.Body) == 0 {
+			return fmt.Errorf("snake %d has no body", i)
+		}
+		if err := onBoard("snake cell", sp.Body...); err != nil {
+			return err
+		}
+		s := NewSnake(slices.Clone(sp.Body), sp.Direction)
+		s.growing, s.hunger, s.points, s.cause = sp.Growing, sp.Hunger, sp.Points, sp.Cause
+		snakes[i] = s
+	}
+
+	board := newRulesBoard(cfg, rand.New(rand.NewSource(g.seed)), snakes)
+	for _, f := range p.Foods {
+		if err := onBoard("food", f.At); err != nil {
+			return err
+		}
+		board.foods = append(board.foods, f)
+	}
+	board.gameOver = board.over()
+
+	g.board = board
+	return nil
+}
+
+// Position is the game as it stands, every snake included, the dead ones too
+func (g *Game) Position() Position {
+	b := g.board
+	p := Position{
+		Rows:  b.rows,
+		Cols:  b.cols,
+		Walls: b.walls,
+		Foods: slices.Clone(b.foods),
+	}
+	for _, s := range b.snakes {
+		p.Snakes = append(p.Snakes, SnakePosition{
+			Body:      slices.Clone(s.body),
+			Direction: s.direction,
+			Growing:   s.growing,
+			Hunger:    s.hunger,
+			Points:    s.points,
+			Cause:     s.cause,
+		})
+	}
+	for q := range b.obstacles {
+		p.Obstacles = append(p.Obstacles, q)
+	}
+	// Row by row, so the same board always comes out the same
+	slices.SortFunc(p.Obstacles, func(a, b model.Point) int {
+		if a.X != b.X {
+			return a.X - b.X
+		}
+		return a.Y - b.Y
+	})
+	return p
+}
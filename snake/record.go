@@ -0,0 +1,239 @@
+package snake
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/casen/snakegame/model"
+)
+
+// Recorder writes down the episodes of a game as JSON lines. Each episode
+// starts with a line holding its seed and the rules it's played by, followed
+// by a line for every tick: the action of each player as one of "ENSW", or
+// "." for keeping on, the reward of each player, and the Q-values the agent
+// saw when they're given. The food of an episode is placed by its seed alone,
+// so that's all it takes to play the episode back exactly, see Replayer.
+//
+//	{"seed":42,"config":{"Rows":20,"Cols":20,...}}
+//	{"a":"E","r":[2],"q":[0.1,0.3,-0.2,0.05]}
+//	{"a":"S","r":[-4]}
+type Recorder struct {
+	w       *bufio.Writer
+	enc     *json.Encoder
+	qvalues []float32
+	err     error
+
+	// Whether an episode has started, the ticks of one already under way
+	// when recording began are left out
+	started bool
+}
+
+func NewRecorder(w io.Writer) *Recorder {
+	bw := bufio.NewWriter(w)
+	return &Recorder{w: bw, enc: json.NewEncoder(bw)}
+}
+
+// QValues has the Q-values the agent picked its move by written down with
+// the next tick
+func (r *Recorder) QValues(q []float32) {
+	r.qvalues = q
+}
+
+// Flush writes out whatever is buffered, and reports the first error the
+// recorder ran into
+func (r *Recorder) Flush() error {
+	if r.err == nil {
+		r.err = r.w.Flush()
+	}
+	return r.err
+}
+
+func (r *Recorder) episode(cfg Config, seed int64) {
+	r.qvalues = nil
+	r.started = true
+	rc := recordConfig(cfg)
+	r.write(recordLine{Seed: &seed, Config: &rc})
+}
+
+func (r *Recorder) tick(actions []model.Vector, results []StepResult) {
+	if !r.started {
+		return
+	}
+	line := recordLine{Actions: encodeActions(actions, len(results)), QValues: r.qvalues}
+	for _, result := range results {
+		line.Rewards = append(line.Rewards, result.Reward)
+	}
+	r.qvalues = nil
+	r.write(line)
+}
+
+func (r *Recorder) write(line recordLine) {
+	if r.err == nil {
+		r.err = r.enc.Encode(line)
+	}
+}
+
+// A line of a recording: the header of an episode when it has a config, a
+// tick otherwise
+type recordLine struct {
+	Seed    *int64         `json:"seed,omitempty"`
+	Config  *recordedRules `json:"config,omitempty"`
+	Actions string         `json:"a,omitempty"`
+	Rewards []float32      `json:"r,omitempty"`
+	QValues []float32      `json:"q,omitempty"`
+}
+
+// The parts of a Config that decide how the board plays out. What the agent
+// sees and what moves are worth to it are up to whoever plays the recording
+// back.
+type recordedRules struct {
+	Rows            int
+	Cols            int
+	Snake           []model.Point
+	Direction       model.Vector
+	Rivals          []Start `json:",omitempty"`
+	Growth          int
+	Walls           WallMode
+	Obstacles       []model.Point `json:",omitempty"`
+	Foods           []FoodRule
+	FoodSpots       []model.Point `json:",omitempty"`
+	Starvation      StarveRule
+	EndLoops        bool `json:",omitempty"`
+	MaxMoves        int  `json:",omitempty"`
+	Speed           []SpeedStep
+	RelativeActions bool
+}
+
+func recordConfig(cfg Config) recordedRules {
+	return recordedRules{
+		Rows:            cfg.Rows,
+		Cols:            cfg.Cols,
+		Snake:           cfg.Snake,
+		Direction:       cfg.Direction,
+		Rivals:          cfg.Rivals,
+		Growth:          cfg.Growth,
+		Walls:           cfg.Walls,
+		Obstacles:       cfg.Obstacles,
+		Foods:           cfg.Foods,
+		FoodSpots:       cfg.FoodSpots,
+		Starvation:      cfg.Starvation,
+		EndLoops:        cfg.EndLoops,
+		MaxMoves:        cfg.MaxMoves,
+		Speed:           cfg.Speed,
+		RelativeActions: cfg.RelativeActions,
+	}
+}
+
+// The config the rules were recorded from, with the default encoder and
+// rewards
+func (rc recordedRules) config(seed int64) Config {
+	cfg := DefaultConfig()
+	cfg.Rows, cfg.Cols = rc.Rows, rc.Cols
+	cfg.Snake, cfg.Direction, cfg.Rivals = rc.Snake, rc.Direction, rc.Rivals
+	cfg.Growth = rc.Growth
+	cfg.Walls = rc.Walls
+	cfg.Obstacles = rc.Obstacles
+	cfg.Foods = rc.Foods
+	cfg.FoodSpots = rc.FoodSpots
+	cfg.Starvation = rc.Starvation
+	cfg.EndLoops, cfg.MaxMoves = rc.EndLoops, rc.MaxMoves
+	cfg.Speed = rc.Speed
+	cfg.RelativeActions = rc.RelativeActions
+	cfg.Seed = seed
+	return cfg
+}
+
+// The letters actions are written down as, in the order of Directions, and
+// the one for keeping on
+const (
+	actionLetters = "ENSW"
+	keepOn        = '.'
+)
+
+func encodeActions(actions []model.Vector, players int) string {
+	var sb strings.Builder
+	for i := 0; i < players; i++ {
+		if i >= len(actions) || actions[i] == (model.Vector{}) {
+			sb.WriteByte(keepOn)
+			continue
+		}
+		sb.WriteByte(actionLetters[directionIndex(actions[i])])
+	}
+	return sb.String()
+}
+
+func decodeActions(s string) ([]model.Vector, error) {
+	actions := make([]model.Vector, len(s))
+	for i, c := range []byte(s) {
+		if c == keepOn {
+			continue
+		}
+		d := strings.IndexByte(actionLetters, c)
+		if d < 0 {
+			return nil, fmt.Errorf("unknown action %q", c)
+		}
+		actions[i] = Directions[d]
+	}
+	return actions, nil
+}
+
+// Episode is an episode as it was recorded
+type Episode struct {
+	Config Config // seeded with the seed of the episode
+	Ticks  []Tick
+}
+
+// Tick is what was recorded of a tick of an episode
+type Tick struct {
+	Actions []model.Vector
+	Rewards []float32
+	QValues []float32 // nil unless the agent's were recorded
+}
+
+// ReadRecording reads the episodes a Recorder wrote down
+func ReadRecording(r io.Reader) ([]Episode, error) {
+	var episodes []Episode
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for n := 1; scanner.Scan(); n++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+
+		var line recordLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+
+		if line.Config != nil {
+			if line.Seed == nil {
+				return nil, fmt.Errorf("line %d: episode has no seed", n)
+			}
+			episodes = append(episodes, Episode{Config: line.Config.config(*line.Seed)})
+			continue
+		}
+
+		if len(episodes) == 0 {
+			return nil, fmt.Errorf("line %d: tick before the first episode", n)
+		}
+		actions, err := decodeActions(line.Actions)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		ep := &episodes[len(episodes)-1]
+		ep.Ticks = append(ep.Ticks, Tick{Actions: actions, Rewards: line.Rewards, QValues: line.QValues})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(episodes) == 0 {
+		return nil, errors.New("recording has no episodes")
+	}
+	return episodes, nil
+}
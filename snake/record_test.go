@@ -0,0 +1,140 @@
+package snake
+
+import (
+	"bytes"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/casen/snakegame/model"
+)
+
+// What a board looks like, enough to tell two apart
+type snapshot struct {
+	body  []model.Point
+	foods []Food
+	score int
+}
+
+func snap(b *Board) snapshot {
+	return snapshot{slices.Clone(b.snake.body), slices.Clone(b.foods), b.Score()}
+}
+
+func (s snapshot) equal(o snapshot) bool {
+	return slices.Equal(s.body, o.body) && slices.Equal(s.foods, o.foods) && s.score == o.score
+}
+
+func TestReplayMatchesTheGame(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Seed = 5
+	cfg.Foods = append(cfg.Foods, FoodRules[BonusFood], FoodRules[PoisonFood])
+	game := newTestGame(t, cfg)
+
+	// The second episode, so its seed isn't the config's
+	game.Reset()
+	var buf bytes.Buffer
+	recorder := NewRecorder(&buf)
+	game.Record(recorder)
+
+	rng := rand.New(rand.NewSource(1))
+	want := []snapshot{snap(game.board)}
+	for !game.GameOver() && len(want) < 300 {
+		recorder.QValues([]float32{1, 2, 3, 4})
+		game.Step(Directions[rng.Intn(len(Directions))])
+		want = append(want, snap(game.board))
+	}
+	if err := recorder.Flush(); err != nil {
+		t.Fatalf("Flush() = %v", err)
+	}
+
+	episodes, err := ReadRecording(&buf)
+	if err != nil {
+		t.Fatalf("ReadRecording() = %v", err)
+	}
+	if len(episodes) != 1 || episodes[0].Config.Seed != game.Seed() || len(episodes[0].Ticks) != len(want)-1 {
+		t.Fatalf("ReadRecording() = %d episodes; want 1 of %d ticks seeded %d", len(episodes), len(want)-1, game.Seed())
+	}
+	if q := episodes[0].Ticks[0].QValues; !slices.Equal(q, []float32{1, 2, 3, 4}) {
+		t.Errorf("QValues = %v; want them as recorded", q)
+	}
+
+	replayer, err := NewReplayer(episodes[0])
+	if err != nil {
+		t.Fatalf("NewReplayer() = %v", err)
+	}
+	for tick := 0; ; tick++ {
+		if got := snap(replayer.Game().board); !got.equal(want[tick]) {
+			t.Fatalf("tick %d: replayed %+v; want %+v", tick, got, want[tick])
+		}
+		if !replayer.Forward() {
+			break
+		}
+	}
+	if replayer.Tick() != replayer.Len() || !replayer.Game().GameOver() {
+		t.Errorf("replay ended at tick %d of %d; want the end of the game", replayer.Tick(), replayer.Len())
+	}
+
+	// Stepping back and seeking show the boards already worked out
+	replayer.Back()
+	if got := snap(replayer.Game().board); !got.equal(want[len(want)-2]) {
+		t.Errorf("Back() = %+v; want %+v", got, want[len(want)-2])
+	}
+	for _, tick := range []int{0, len(want) / 2, -5, len(want) + 5} {
+		replayer.Seek(tick)
+		wantTick := max(0, min(tick, len(want)-1))
+		if got := snap(replayer.Game().board); replayer.Tick() != wantTick || !got.equal(want[wantTick]) {
+			t.Errorf("Seek(%d) = tick %d %+v; want tick %d %+v", tick, replayer.Tick(), got, wantTick, want[wantTick])
+		}
+	}
+}
+
+func TestRecordingEveryEpisode(t *testing.T) {
+	game := newTestGame(t, DefaultConfig())
+	game.Step(model.Vector{})
+
+	// Recording starts with the next episode, the first is under way
+	var buf bytes.Buffer
+	recorder := NewRecorder(&buf)
+	game.Record(recorder)
+	game.Step(model.Vector{})
+	for i := 0; i < 3; i++ {
+		game.Reset()
+		game.Step(southVector)
+	}
+	recorder.Flush()
+
+	episodes, err := ReadRecording(&buf)
+	if err != nil {
+		t.Fatalf("ReadRecording() = %v", err)
+	}
+	if len(episodes) != 3 {
+		t.Fatalf("ReadRecording() = %d episodes; want 3", len(episodes))
+	}
+	for _, ep := range episodes {
+		if len(ep.Ticks) != 1 || ep.Ticks[0].Actions[0] != southVector {
+			t.Errorf("Ticks = %+v; want the one step south", ep.Ticks)
+		}
+	}
+}
+
+func TestReadRecordingErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		text string
+	}{
+		{"Empty", ""},
+		{"Not JSON", "seed 1\n"},
+		{"Tick first", `{"a":"E","r":[1]}` + "\n"},
+		{"No seed", `{"config":{"Rows":20}}` + "\n"},
+		{"Unknown action", `{"seed":1,"config":{"Rows":20}}` + "\n" + `{"a":"X"}` + "\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ReadRecording(strings.NewReader(tc.text)); err == nil {
+				t.Errorf("ReadRecording() = nil; want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package snake
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"unicode"
+)
+
+var (
+	backgroundColor = color.RGBA{50, 100, 50, 50}
+	// By player, taking turns when there are more players than colors
+	snakeColors = []color.RGBA{
+		{0, 255, 0, 255},
+		{255, 80, 80, 255},
+		{80, 140, 255, 255},
+		{255, 255, 255, 255},
+	}
+	foodColors = [foodKinds]color.RGBA{
+		NormalFood: {200, 200, 50, 150},
+		BonusFood:  {255, 140, 0, 255},
+		PoisonFood: {160, 40, 160, 255},
+		SpeedFood:  {50, 200, 255, 255},
+	}
+	portalColor   = color.RGBA{80, 160, 220, 255}
+	obstacleColor = color.RGBA{120, 90, 60, 255}
+	textColor     = color.RGBA{255, 255, 255, 255}
+)
+
+// How thick the edges of a board that wraps around are drawn
+const portalWidth = 2
+
+// Render draws the game the way Draw puts it on the screen, but into an image
+// of its own, so it needs neither ebiten nor a display. Cells are cell pixels
+// wide, or as wide as on the screen when cell is 0.
+func (g *Game) Render(cell int) *image.RGBA {
+	if cell <= 0 {
+		cell = g.cellSize()
+	}
+	img := image.NewRGBA(image.Rect(0, 0, g.config.Cols*cell, g.config.Rows*cell))
+
+	// The window shows the background as if it were opaque
+	bg := backgroundColor
+	bg.A = 255
+	draw.Draw(img, img.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+
+	if g.board.gameOver {
+		drawText(img, "Game Over. "+g.scores())
+		return img
+	}
+
+	for p := range g.board.obstacles {
+		fillCell(img, p.X, p.Y, cell, obstacleColor)
+	}
+	for i, s := range g.board.snakes {
+		if !s.alive() {
+			continue
+		}
+		for _, p := range s.body {
+			fillCell(img, p.X, p.Y, cell, snakeColors[i%len(snakeColors)])
+		}
+	}
+	for _, f := range g.board.foods {
+		fillCell(img, f.At.X, f.At.Y, cell, foodColors[f.Kind])
+	}
+	if g.config.Walls == WrapAround {
+		renderPortals(img)
+	}
+	drawText(img, g.scores())
+	return img
+}
+
+// The score, or the score of each player when there's more than one
+func (g *Game) scores() string {
+	if g.Players() == 1 {
+		return fmt.Sprintf("Score: %d", g.Score())
+	}
+	var sb strings.Builder
+	for i := 0; i < g.Players(); i++ {
+		if i > 0 {
+			sb.WriteString("  ")
+		}
+		fmt.Fprintf(&sb, "P%d: %d", i+1, g.ScoreOf(i))
+	}
+	return sb.String()
+}
+
+func fillCell(img *image.RGBA, row, col, cell int, c color.RGBA) {
+	r := image.Rect(col*cell, row*cell, (col+1)*cell, (row+1)*cell)
+	draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Over)
+}
+
+// Marks the edges of the board like drawPortals does on the screen
+func renderPortals(img *image.RGBA) {
+	b := img.Bounds()
+	edges := []image.Rectangle{
+		image.Rect(b.Min.X, b.Min.Y, b.Max.X, b.Min.Y+portalWidth),
+		image.Rect(b.Min.X, b.Max.Y-portalWidth, b.Max.X, b.Max.Y),
+		image.Rect(b.Min.X, b.Min.Y, b.Min.X+portalWidth, b.Max.Y),
+		image.Rect(b.Max.X-portalWidth, b.Min.Y, b.Max.X, b.Max.Y),
+	}
+	for _, r := range edges {
+		draw.Draw(img, r, image.NewUniform(portalColor), image.Point{}, draw.Over)
+	}
+}
+
+// The text is drawn from a font of 3x5 pixel glyphs, each pixel of them
+// taking up glyphScale pixels square, in the top left corner like the debug
+// text on the screen
+const (
+	glyphScale   = 2
+	glyphAdvance = 4 * glyphScale
+	textMargin   = 2
+)
+
+// By character, a row of pixels to a string. Lower case letters are drawn as
+// upper case ones, and characters without a glyph as spaces.
+var glyphs = map[rune][5]string{
+	'0': {"###", "#.#", "#.#", "#.#", "###"},
+	'1': {".#.", "##.", ".#.", ".#.", "###"},
+	'2': {"###", "..#", "###", "#..", "###"},
+	'3': {"###", "..#", ".##", "..#", "###"},
+	'4': {"#.#", "#.#", "###", "..#", "..#"},
+	'5': {"###", "#..", "###", "..#", "###"},
+	'6': {"###", "#..", "###", "#.#", "###"},
+	'7': {"###", "..#", "..#", ".#.", ".#."},
+	'8': {"###", "#.#", "###", "#.#", "###"},
+	'9': {"###", "#.#", "###", "..#", "###"},
+	'A': {".#.", "#.#", "###", "#.#", "#.#"},
+	'B': {"##.", "#.#", "##.", "#.#", "##."},
+	'C': {".##", "#..", "#..", "#..", ".##"},
+	'D': {"##.", "#.#", "#.#", "#.#", "##."},
+	'E': {"###", "#..", "##.", "#..", "###"},
+	'F': {"###", "#..", "##.", "#..", "#.."},
+	'G': {".##", "#..", "#.#", "#.#", ".##"},
+	'H': {"#.#", "#.#", "###", "#.#", "#.#"},
+	'I': {"###", ".#.", ".#.", ".#.", "###"},
+	'J': {"..#", "..#", "..#", "#.#", ".#."},
+	'K': {"#.#", "#.#", "##.", "#.#", "#.#"},
+	'L': {"#..", "#..", "#..", "#..", "###"},
+	'M': {"#.#", "###", "###", "#.#", "#.#"},
+	'N': {"##.", "#.#", "#.#", "#.#", "#.#"},
+	'O': {".#.", "#.#", "#.#", "#.#", ".#."},
+	'P': {"##.", "#.#", "##.", "#..", "#.."},
+	'Q': {".#.", "#.#", "#.#", "##.", ".##"},
+	'R': {"##.", "#.#", "##.", "#.#", "#.#"},
+	'S': {".##", "#..", ".#.", "..#", "##."},
+	'T': {"###", ".#.", ".#.", ".#.", ".#."},
+	'U': {"#.#", "#.#", "#.#", "#.#", "###"},
+	'V': {"#.#", "#.#", "#.#", "#.#", ".#."},
+	'W': {"#.#", "#.#", "###", "###", "#.#"},
+	'X': {"#.#", "#.#", ".#.", "#.#", "#.#"},
+	'Y': {"#.#", "#.#", ".#.", ".#.", ".#."},
+	'Z': {"###", "..#", ".#.", "#..", "###"},
+	':': {"...", ".#.", "...", ".#.", "..."},
+	'.': {"...", "...", "...", "...", ".#."},
+	',': {"...", "...", "...", ".#.", "#.."},
+	'-': {"...", "...", "###", "...", "..."},
+	'/': {"..#", "..#", ".#.", "#..", "#.."},
+	'(': {"..#", ".#.", ".#.", ".#.", "..#"},
+	')': {"#..", ".#.", ".#.", ".#.", "#.."},
+}
+
+// Draws a line of text over the top left corner of the image
+func drawText(img *image.RGBA, s string) {
+	x := textMargin
+	for _, c := range s {
+		glyph := glyphs[unicode.ToUpper(c)]
+		for row, line := range glyph {
+			for col := range line {
+				if line[col] != '#' {
+					continue
+				}
+				px := image.Rect(x+col*glyphScale, textMargin+row*glyphScale, x+(col+1)*glyphScale, textMargin+(row+1)*glyphScale)
+				draw.Draw(img, px, image.NewUniform(textColor), image.Point{}, draw.Src)
+			}
+		}
+		x += glyphAdvance
+	}
+}
@@ -0,0 +1,113 @@
+package snake
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+
+	"github.com/casen/snakegame/model"
+)
+
+func TestRenderCells(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Seed = 3
+	cfg.Obstacles = []model.Point{{X: 0, Y: 19}}
+	game := newTestGame(t, cfg)
+	game.board.foods = []Food{{Kind: BonusFood, At: model.Point{X: 15, Y: 15}}}
+
+	const cell = 30
+	img := game.Render(cell)
+	if got, want := img.Bounds(), image.Rect(0, 0, cfg.Cols*cell, cfg.Rows*cell); got != want {
+		t.Fatalf("Render() bounds = %v; want %v", got, want)
+	}
+
+	// The middle of a cell, away from the score in the corner
+	at := func(p model.Point) color.RGBA {
+		return img.RGBAAt(p.Y*cell+cell/2, p.X*cell+cell/2)
+	}
+	bg := backgroundColor
+	bg.A = 255
+	tests := []struct {
+		name string
+		p    model.Point
+		want color.RGBA
+	}{
+		{"Head", game.CurrentLocation(), snakeColors[0]},
+		{"Food", model.Point{X: 15, Y: 15}, foodColors[BonusFood]},
+		{"Obstacle", model.Point{X: 0, Y: 19}, obstacleColor},
+		{"Empty", model.Point{X: 19, Y: 0}, bg},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := at(tt.p); got != tt.want {
+				t.Errorf("Render() at %v = %v; want %v", tt.p, got, tt.want)
+			}
+		})
+	}
+
+	// The score is written in the top left corner
+	var text int
+	for y := 0; y < textMargin+5*glyphScale; y++ {
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			if img.RGBAAt(x, y) == textColor {
+				text++
+			}
+		}
+	}
+	if text == 0 {
+		t.Errorf("Render() wrote no score")
+	}
+}
+
+func TestAnimateReplay(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Seed = 9
+	game := newTestGame(t, cfg)
+	var buf bytes.Buffer
+	recorder := NewRecorder(&buf)
+	game.Record(recorder)
+	for i := 0; i < 5; i++ {
+		game.Step(model.Vector{})
+	}
+	if err := recorder.Flush(); err != nil {
+		t.Fatalf("Flush() = %v", err)
+	}
+
+	episodes, err := ReadRecording(&buf)
+	if err != nil {
+		t.Fatalf("ReadRecording() = %v", err)
+	}
+	replayer, err := NewReplayer(episodes[0])
+	if err != nil {
+		t.Fatalf("NewReplayer() = %v", err)
+	}
+	replayer.Seek(2)
+
+	film := replayer.Animate(4)
+	if film.Len() != replayer.Len()+1 {
+		t.Errorf("Animate() = %d frames; want one a board, %d", film.Len(), replayer.Len()+1)
+	}
+	if replayer.Tick() != 2 {
+		t.Errorf("Animate() left the replay at tick %d; want 2", replayer.Tick())
+	}
+
+	var out bytes.Buffer
+	if err := film.WriteGIF(&out); err != nil {
+		t.Fatalf("WriteGIF() = %v", err)
+	}
+	g, err := gif.DecodeAll(&out)
+	if err != nil {
+		t.Fatalf("gif.DecodeAll() = %v", err)
+	}
+	if len(g.Image) != film.Len() {
+		t.Errorf("GIF has %d frames; want %d", len(g.Image), film.Len())
+	}
+	if got, want := g.Image[0].Bounds(), image.Rect(0, 0, cfg.Cols*4, cfg.Rows*4); got != want {
+		t.Errorf("GIF frame bounds = %v; want %v", got, want)
+	}
+	if first, last := g.Delay[0], g.Delay[len(g.Delay)-1]; last != first+gifEndDelay {
+		t.Errorf("GIF delays start at %d and end at %d; want the last held %d longer", first, last, gifEndDelay)
+	}
+}
@@ -0,0 +1,112 @@
+package snake
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Replayer plays a recorded episode back, one board at a time. The boards
+// are worked out again from the seed and the actions, so they come out exactly
+// as they were played, and every board worked out is kept for stepping back.
+type Replayer struct {
+	episode Episode
+	game    *Game // shows the board of the tick it's at
+
+	live   *Board   // the board of the last tick worked out, played on from there
+	boards []*Board // by tick, up to the live one
+	tick   int
+}
+
+func NewReplayer(ep Episode) (*Replayer, error) {
+	if err := ep.Config.Validate(); err != nil {
+		return nil, err
+	}
+	for i, t := range ep.Ticks {
+		if len(t.Actions) != 1+len(ep.Config.Rivals) {
+			return nil, fmt.Errorf("tick %d has %d actions, the episode has %d players", i, len(t.Actions), 1+len(ep.Config.Rivals))
+		}
+	}
+
+	live := NewGameBoard(ep.Config, rand.New(rand.NewSource(ep.Config.Seed)))
+	r := &Replayer{
+		episode: ep,
+		game:    &Game{config: ep.Config, seed: ep.Config.Seed},
+		live:    live,
+		boards:  []*Board{live.Clone()},
+	}
+	r.game.board = r.boards[0]
+	return r, nil
+}
+
+// Game is the game as it was at the current tick, for looking at rather than
+// playing on
+func (r *Replayer) Game() *Game {
+	return r.game
+}
+
+// Tick is how many ticks into the episode the replay is
+func (r *Replayer) Tick() int {
+	return r.tick
+}
+
+// Len is how many ticks the episode lasted
+func (r *Replayer) Len() int {
+	return len(r.episode.Ticks)
+}
+
+// Next is what was recorded of the tick played from the current board, the
+// actions the players took there and what the agent made of it, or false at
+// the end of the episode
+func (r *Replayer) Next() (Tick, bool) {
+	if r.tick >= r.Len() {
+		return Tick{}, false
+	}
+	return r.episode.Ticks[r.tick], true
+}
+
+// Forward steps the replay one tick forward, reporting false at the end of
+// the episode
+func (r *Replayer) Forward() bool {
+	if r.tick >= r.Len() {
+		return false
+	}
+	r.Seek(r.tick + 1)
+	return true
+}
+
+// Back steps the replay one tick back, reporting false at the start of the
+// episode
+func (r *Replayer) Back() bool {
+	if r.tick == 0 {
+		return false
+	}
+	r.Seek(r.tick - 1)
+	return true
+}
+
+// Seek takes the replay to the given tick, or as far as the episode goes
+func (r *Replayer) Seek(tick int) {
+	tick = max(0, min(tick, r.Len()))
+	for len(r.boards) <= tick {
+		r.live.StepAll(r.episode.Ticks[len(r.boards)-1].Actions)
+		r.boards = append(r.boards, r.live.Clone())
+	}
+	r.tick = tick
+	r.game.board = r.boards[tick]
+}
+
+// Animate draws every board of the episode, from the first to the last, into
+// an animation with cells of the given size, see Game.Render. The replay is
+// left at the tick it was at.
+func (r *Replayer) Animate(cell int) *Animation {
+	at := r.tick
+	defer r.Seek(at)
+
+	a := &Animation{Cell: cell}
+	for r.Seek(0); ; r.Forward() {
+		a.Add(r.game)
+		if r.tick == r.Len() {
+			return a
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package snake
+
+// Event is what came of a move: the snake ate, died, or merely moved when
+// neither is set
+type Event struct {
+	Ate    bool
+	Food   FoodKind // what the snake ate
+	Points int      // what it scored for it
+	Died   bool
+	Cause  Cause // why the snake died
+}
+
+// RewardFunc decides what a move is worth to the agent, from the board before
+// and after the move and what happened on it
+type RewardFunc interface {
+	Reward(before, after *Board, ev Event) float32
+}
+
+// RewardTable is the shaped scheme the agent learnt on first: besides eating
+// and dying, every move is worth something depending on whether it brought the
+// snake closer to the food
+type RewardTable struct {
+	Food    float32 // eating the food, for every point it's worth
+	Poison  float32 // eating a poison and living
+	Death   float32 // running into a wall or the snake
+	Closer  float32 // moving closer to the food
+	Farther float32 // moving away from the food
+	Neutral float32 // anything else
+}
+
+var DefaultRewards = RewardTable{
+	Food:    100,
+	Poison:  -50,
+	Death:   -100,
+	Closer:  2,
+	Farther: -4,
+	Neutral: -1,
+}
+
+func (t RewardTable) Reward(before, after *Board, ev Event) float32 {
+	if ev.Died {
+		return t.Death
+	}
+	if ev.Ate && ev.Food == PoisonFood {
+		return t.Poison
+	}
+	if ev.Ate {
+		return t.Food * float32(ev.Points)
+	}
+
+	currentDistance := before.distance(before.Head(), before.Food())
+	nextDistance := before.distance(after.Head(), before.Food())
+	switch {
+	case nextDistance < currentDistance:
+		return t.Closer
+	case nextDistance > currentDistance:
+		return t.Farther
+	default:
+		return t.Neutral
+	}
+}
+
+// Sparse only rewards eating and dying, leaving the agent to work out the
+// rest on its own
+type Sparse struct {
+	Food  float32
+	Death float32
+}
+
+func (s Sparse) Reward(before, after *Board, ev Event) float32 {
+	switch {
+	case ev.Died:
+		return s.Death
+	case ev.Ate && ev.Food != PoisonFood:
+		return s.Food * float32(ev.Points)
+	default:
+		return 0
+	}
+}
+
+// SurvivalBonus adds Bonus to Rewards for every move the snake lives through
+type SurvivalBonus struct {
+	Rewards RewardFunc
+	Bonus   float32
+}
+
+func (s SurvivalBonus) Reward(before, after *Board, ev Event) float32 {
+	reward := s.Rewards.Reward(before, after, ev)
+	if !ev.Died {
+		reward += s.Bonus
+	}
+	return reward
+}
+
+// StarvationPenalty adds Penalty to Rewards for every move the snake makes
+// once it has gone more than After moves without eating, so that circling
+// around the board safely stops paying off
+type StarvationPenalty struct {
+	Rewards RewardFunc
+	After   int
+	Penalty float32
+}
+
+func (s StarvationPenalty) Reward(before, after *Board, ev Event) float32 {
+	reward := s.Rewards.Reward(before, after, ev)
+	if !ev.Died && after.Hunger() > s.After {
+		reward += s.Penalty
+	}
+	return reward
+}
@@ -0,0 +1,92 @@
+package snake
+
+import (
+	"testing"
+
+	"github.com/casen/snakegame/model"
+)
+
+func TestRewardFuncs(t *testing.T) {
+	// Heading east along the second row, food two cells ahead
+	newBoard := func(rewards RewardFunc) *Board {
+		board := NewBoard(
+			10,
+			10,
+			NewSnake([]model.Point{{X: 1, Y: 0}, {X: 1, Y: 1}, {X: 1, Y: 2}}, eastVector),
+			model.Point{X: 1, Y: 4},
+		)
+		board.rewards = rewards
+		return board
+	}
+
+	sparse := Sparse{Food: 10, Death: -10}
+	testCases := []struct {
+		name    string
+		rewards RewardFunc
+		moves   []model.Vector
+		want    float32
+	}{
+		{"Shaped, closer", DefaultRewards, []model.Vector{eastVector}, DefaultRewards.Closer},
+		{"Shaped, farther", DefaultRewards, []model.Vector{southVector}, DefaultRewards.Farther},
+		{"Shaped, food", DefaultRewards, []model.Vector{eastVector, eastVector}, DefaultRewards.Food},
+		{"Shaped, death", DefaultRewards, []model.Vector{northVector, northVector}, DefaultRewards.Death},
+		{"Sparse, moving", sparse, []model.Vector{eastVector}, 0},
+		{"Sparse, food", sparse, []model.Vector{eastVector, eastVector}, 10},
+		{"Sparse, death", sparse, []model.Vector{northVector, northVector}, -10},
+		{"Survival, moving", SurvivalBonus{sparse, 0.5}, []model.Vector{southVector}, 0.5},
+		{"Survival, food", SurvivalBonus{sparse, 0.5}, []model.Vector{eastVector, eastVector}, 10.5},
+		{"Survival, death", SurvivalBonus{sparse, 0.5}, []model.Vector{northVector, northVector}, -10},
+		{"Starvation, fed", StarvationPenalty{sparse, 2, -1}, []model.Vector{southVector, southVector}, 0},
+		{"Starvation, hungry", StarvationPenalty{sparse, 2, -1}, []model.Vector{southVector, southVector, southVector}, -1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			board := newBoard(tc.rewards)
+			var result StepResult
+			for _, move := range tc.moves {
+				result = board.Step(move)
+			}
+			if result.Reward != tc.want {
+				t.Errorf("Step() reward = %v; want %v", result.Reward, tc.want)
+			}
+		})
+	}
+}
+
+func TestHunger(t *testing.T) {
+	board := NewBoard(
+		10,
+		10,
+		NewSnake([]model.Point{{X: 1, Y: 0}, {X: 1, Y: 1}, {X: 1, Y: 2}}, eastVector),
+		model.Point{X: 1, Y: 4},
+	)
+
+	board.Step(eastVector)
+	if board.Hunger() != 1 {
+		t.Errorf("Hunger() after a move = %d; want 1", board.Hunger())
+	}
+
+	board.Step(eastVector)
+	if board.Hunger() != 0 {
+		t.Errorf("Hunger() after eating = %d; want 0", board.Hunger())
+	}
+
+	if clone := board.Clone(); clone.Hunger() != board.Hunger() {
+		t.Errorf("Clone().Hunger() = %d; want %d", clone.Hunger(), board.Hunger())
+	}
+}
+
+func TestEnvReportsEvents(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Rewards = Sparse{Food: 1, Death: -1}
+	env := NewEnv(newTestGame(t, cfg))
+
+	// Heading north from the top row runs into the wall
+	if _, _, _, info := env.Peek(model.Action(1)); !info.Died || info.Scored {
+		t.Errorf("Peek(N) info = %+v; want died", info)
+	}
+	if _, _, _, info := env.Peek(model.Action(0)); info.Died {
+		t.Errorf("Peek(E) info = %+v; want alive", info)
+	}
+}
@@ -11,7 +11,11 @@ type Coord struct {
 type Snake struct {
 	body      []model.Point
 	direction model.Vector
-	justAte   bool
+	growing   int // cells still to grow, one per move
+
+	points int
+	hunger int   // moves since the snake last ate
+	cause  Cause // why the snake died, CauseNone while it's alive
 }
 
 func NewSnake(body []model.Point, direction model.Vector) *Snake {
@@ -90,16 +94,25 @@ func (s *Snake) Move() {
 	h := s.Head()
 	newHead := model.Point{X: h.X + s.direction.X, Y: h.Y + s.direction.Y}
 
-	if s.justAte {
+	if s.growing > 0 {
 		s.body = append(s.body, newHead)
-		s.justAte = false
+		s.growing--
 	} else {
 		s.body = append(s.body[1:], newHead)
 	}
 }
 
+func (s *Snake) alive() bool {
+	return s.cause == CauseNone
+}
+
 func (s *Snake) Clone() *Snake {
 	bodyClone := make([]model.Point, len(s.body))
 	copy(bodyClone, s.body)
-	return NewSnake(bodyClone, s.direction)
+	clone := NewSnake(bodyClone, s.direction)
+	clone.growing = s.growing
+	clone.points = s.points
+	clone.hunger = s.hunger
+	clone.cause = s.cause
+	return clone
 }
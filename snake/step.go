@@ -0,0 +1,52 @@
+package snake
+
+// Cause is the reason a game came to an end
+type Cause int
+
+const (
+	CauseNone     Cause = iota // the game is still running
+	CauseWall                  // the snake ran off the board
+	CauseSelf                  // the snake ran into its own body
+	CauseStarved               // the snake went too long without eating
+	CauseLooped                // the game came back round to a board it had been on
+	CauseTimedOut              // the game ran for the most moves it may take
+	CauseObstacle              // the snake ran into an obstacle
+	CausePoisoned              // the snake ate a poison with nothing left to lose
+	CauseSnake                 // the snake ran into the body of another snake
+	CauseHeadOn                // the snake met another head on, and wasn't the longer
+)
+
+func (c Cause) String() string {
+	switch c {
+	case CauseNone:
+		return "none"
+	case CauseWall:
+		return "wall"
+	case CauseSelf:
+		return "self"
+	case CauseStarved:
+		return "starved"
+	case CauseLooped:
+		return "looped"
+	case CauseTimedOut:
+		return "timed out"
+	case CauseObstacle:
+		return "obstacle"
+	case CausePoisoned:
+		return "poisoned"
+	case CauseSnake:
+		return "snake"
+	case CauseHeadOn:
+		return "head-on"
+	default:
+		return "unknown"
+	}
+}
+
+// StepResult describes what happened during a single tick of the game
+type StepResult struct {
+	Reward  float32
+	Done    bool
+	AteFood bool // ate a food worth eating, poison doesn't count
+	Cause   Cause
+}
@@ -0,0 +1,90 @@
+package snake
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+)
+
+// How the cells are marked in plain text, where there's no color to tell them
+// apart. The first snake, the food and obstacles are marked as in a level,
+// the other snakes by their player number.
+const (
+	textEmpty    = cellEmpty
+	textObstacle = cellObstacle
+	textSnake    = cellSnake
+)
+
+var textFoods = [foodKinds]byte{
+	NormalFood: cellFood,
+	BonusFood:  'B',
+	PoisonFood: 'P',
+	SpeedFood:  '>',
+}
+
+// WriteText writes the game out as text, the board and then the score, for
+// terminals. Every cell takes two characters, so the board comes out about
+// square. With ansi set the cells are painted in the colors Draw uses, with
+// ANSI escape codes, otherwise they're told apart by their characters.
+func (g *Game) WriteText(w io.Writer, ansi bool) error {
+	var sb strings.Builder
+	g.board.writeText(&sb, ansi)
+	if g.board.gameOver {
+		sb.WriteString("Game Over. ")
+	}
+	sb.WriteString(g.scores())
+	sb.WriteByte('\n')
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func (b *Board) writeText(sb *strings.Builder, ansi bool) {
+	type cell struct {
+		c    byte
+		fill color.RGBA
+	}
+	bg := backgroundColor
+	bg.A = 255
+	grid := make([][]cell, b.rows)
+	for i := range grid {
+		grid[i] = make([]cell, b.cols)
+		for j := range grid[i] {
+			grid[i][j] = cell{textEmpty, bg}
+		}
+	}
+
+	for p := range b.obstacles {
+		grid[p.X][p.Y] = cell{textObstacle, obstacleColor}
+	}
+	for i, s := range b.snakes {
+		if !s.alive() {
+			continue
+		}
+		c := byte(textSnake)
+		if i > 0 {
+			c = byte('1' + i%9)
+		}
+		for _, p := range s.body {
+			grid[p.X][p.Y] = cell{c, snakeColors[i%len(snakeColors)]}
+		}
+	}
+	for _, f := range b.foods {
+		grid[f.At.X][f.At.Y] = cell{textFoods[f.Kind], over(foodColors[f.Kind], bg)}
+	}
+
+	for _, row := range grid {
+		for _, c := range row {
+			if ansi {
+				fmt.Fprintf(sb, "\x1b[48;2;%d;%d;%dm  ", c.fill.R, c.fill.G, c.fill.B)
+			} else {
+				sb.WriteByte(c.c)
+				sb.WriteByte(' ')
+			}
+		}
+		if ansi {
+			sb.WriteString("\x1b[0m")
+		}
+		sb.WriteByte('\n')
+	}
+}
@@ -0,0 +1,43 @@
+package snake
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/casen/snakegame/model"
+)
+
+func TestWriteText(t *testing.T) {
+	board := NewBoard(4, 5, NewSnake([]model.Point{{X: 0, Y: 0}, {X: 0, Y: 1}, {X: 0, Y: 2}}, eastVector), model.Point{X: 3, Y: 4})
+	board.snakes = append(board.snakes, NewSnake([]model.Point{{X: 2, Y: 3}, {X: 2, Y: 2}}, westVector))
+	board.foods = append(board.foods, Food{Kind: PoisonFood, At: model.Point{X: 1, Y: 4}})
+	board.obstacles = map[model.Point]bool{{X: 3, Y: 0}: true}
+	game := &Game{board: board, config: Config{Rows: 4, Cols: 5}}
+
+	var sb strings.Builder
+	if err := game.WriteText(&sb, false); err != nil {
+		t.Fatalf("WriteText() = %v", err)
+	}
+	want := strings.Join([]string{
+		"S S S . . ",
+		". . . . P ",
+		". . 2 2 . ",
+		"# . . . F ",
+		"P1: 0  P2: 0",
+	}, "\n") + "\n"
+	if got := sb.String(); got != want {
+		t.Errorf("WriteText() =\n%s\nwant\n%s", got, want)
+	}
+
+	// In color every cell is painted, and nothing is left marked
+	sb.Reset()
+	game.WriteText(&sb, true)
+	if got, want := strings.Count(sb.String(), "\x1b[48;2;"), 4*5; got != want {
+		t.Errorf("WriteText() painted %d cells; want %d", got, want)
+	}
+	cells, _, _ := strings.Cut(sb.String(), "P1:")
+	if rest := regexp.MustCompile("\x1b\\[[0-9;]*m").ReplaceAllString(cells, ""); strings.Trim(rest, " \n") != "" {
+		t.Errorf("WriteText() in color marked cells with %q", rest)
+	}
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/casen/snakegame/agent"
+	"github.com/casen/snakegame/model"
+	"github.com/casen/snakegame/snake"
+)
+
+// TerminalPlayer plays the game in a terminal, drawn with ANSI escape codes
+// and steered from the keyboard, for machines without a display like a server
+// over SSH. It's the terminal's counterpart of GamePlayer: humans play with
+// the arrow keys, and WASD for a second snake, or the agent plays on its own.
+// Space pauses, n steps a tick while paused, r starts over and q or Esc quits.
+type TerminalPlayer struct {
+	game *snake.Game
+	ai   *autoplay // the agent playing on its own, nil when humans play
+
+	// Who steers each snake when humans play, by player
+	players []*controller
+
+	paused bool
+	status string // what happened last, shown under the board
+}
+
+// Creates a terminal player for the game. The agent plays when ai is set,
+// otherwise a human plays the first snake and the second, if there is one, is
+// played by rival or by a second human when rival is nil.
+func NewTerminalPlayer(game *snake.Game, ai *agent.Agent, rival *agent.Agent) *TerminalPlayer {
+	if ai != nil {
+		return &TerminalPlayer{game: game, ai: newAutoplay(game, ai)}
+	}
+	return &TerminalPlayer{game: game, players: newControllers(game, rival)}
+}
+
+// A key read from the terminal: the character typed, or one of the arrows
+type key rune
+
+const (
+	keyUp key = -1 - iota
+	keyDown
+	keyRight
+	keyLeft
+
+	keyInterrupt key = 3 // Ctrl-C, which doesn't stop the program in raw mode
+	keyEscape    key = '\x1b'
+)
+
+// The keys that steer the snakes, by player. A single player can use either.
+var (
+	arrowKeys = map[key]model.Vector{
+		keyUp:    {X: -1, Y: 0},
+		keyDown:  {X: 1, Y: 0},
+		keyLeft:  {X: 0, Y: -1},
+		keyRight: {X: 0, Y: 1},
+	}
+	wasdKeys = map[key]model.Vector{
+		'w': {X: -1, Y: 0},
+		's': {X: 1, Y: 0},
+		'a': {X: 0, Y: -1},
+		'd': {X: 0, Y: 1},
+	}
+)
+
+// Run plays the game, reading keys from in and drawing to out, until q is
+// pressed or reading in fails. In is read the way rawTerminal leaves the
+// terminal, see readKeys.
+func (tp *TerminalPlayer) Run(in io.Reader, out io.Writer) error {
+	done := make(chan struct{})
+	keys := readKeys(in, done)
+	// Whatever is typed once the game is done is left for whoever reads the
+	// terminal next
+	defer func() {
+		close(done)
+		for range keys {
+		}
+	}()
+
+	// Hide the cursor and start from a clear screen
+	fmt.Fprint(out, "\x1b[?25l\x1b[2J")
+	defer fmt.Fprint(out, "\x1b[?25h")
+
+	timer := time.NewTimer(tp.game.Interval())
+	defer timer.Stop()
+	for {
+		if err := tp.draw(out); err != nil {
+			return err
+		}
+
+		select {
+		case k, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			if !tp.press(k) {
+				return nil
+			}
+		case <-timer.C:
+			if !tp.paused {
+				tp.step()
+			}
+			timer.Reset(tp.game.Interval())
+		}
+	}
+}
+
+// Handles a key press, reporting false when it's time to quit
+func (tp *TerminalPlayer) press(k key) bool {
+	switch k {
+	case 'q', keyEscape, keyInterrupt:
+		return false
+	case ' ', 'p':
+		tp.paused = !tp.paused
+	case 'n':
+		if tp.paused {
+			tp.step()
+		}
+	case 'r':
+		tp.reset()
+		tp.status = "Started over"
+	}
+
+	if tp.ai != nil {
+		return true
+	}
+	if dir, ok := arrowKeys[k]; ok {
+		tp.players[0].pending = dir
+	}
+	if dir, ok := wasdKeys[k]; ok {
+		tp.players[len(tp.players)-1].pending = dir
+	}
+	return true
+}
+
+// Steps the game a tick
+func (tp *TerminalPlayer) step() {
+	if tp.ai != nil {
+		if msg := tp.ai.step(); msg != "" {
+			tp.status = msg
+		}
+		return
+	}
+	if tp.game.GameOver() {
+		return
+	}
+
+	actions := make([]model.Vector, len(tp.players))
+	for i, c := range tp.players {
+		actions[i] = c.action()
+	}
+	tp.game.StepAll(actions)
+	if tp.game.GameOver() {
+		tp.status = fmt.Sprintf("Game over (%v), r to play again", tp.game.Cause())
+	}
+}
+
+func (tp *TerminalPlayer) reset() {
+	if tp.ai != nil {
+		tp.ai.reset()
+		return
+	}
+	tp.game.Reset()
+	for _, c := range tp.players {
+		c.pending = model.Vector{}
+	}
+}
+
+// Draws the game over the last frame, from the top left corner of the screen
+func (tp *TerminalPlayer) draw(out io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("\x1b[H")
+	if err := tp.game.WriteText(&sb, true); err != nil {
+		return err
+	}
+
+	help := "space pauses, r starts over, q quits"
+	if tp.paused {
+		help = "Paused: space plays, n steps, r starts over, q quits"
+	}
+	// Each line clears what's left of the longer one drawn before it
+	fmt.Fprintf(&sb, "\x1b[K%s\n\x1b[K%s\n\x1b[J", help, tp.status)
+
+	_, err := io.WriteString(out, sb.String())
+	return err
+}
+
+// Reads keys from in as they come, until done is closed or reading fails,
+// closing the channel once it's stopped reading. In raw mode a read with
+// nothing to read comes back empty after a moment, so an empty read is no key
+// rather than the end, and the reader gets to see done is closed without
+// waiting on a key.
+func readKeys(in io.Reader, done <-chan struct{}) <-chan key {
+	keys := make(chan key)
+	go func() {
+		defer close(keys)
+		var parser keyParser
+		buf := make([]byte, 64)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			n, err := in.Read(buf)
+			if err != nil && err != io.EOF {
+				return
+			}
+			for _, k := range parser.parse(buf[:n]) {
+				select {
+				case keys <- k:
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+	return keys
+}
+
+// The final bytes of the escape sequences of the arrows
+var arrowSequences = map[byte]key{'A': keyUp, 'B': keyDown, 'C': keyRight, 'D': keyLeft}
+
+// keyParser turns what's read from the terminal into keys. Keys that send
+// escape sequences, ESC [ or ESC O and on to a final letter, usually send
+// them in one read, but a sequence can be split across reads, so an
+// unfinished one is held back for the next. An ESC nothing has followed by
+// the time a read comes back empty is the Esc key. Sequences other than the
+// arrows are left out.
+type keyParser struct {
+	pending []byte // the start of a sequence still to be finished
+}
+
+// The keys in what a read brought in, b, along with what was held back
+func (p *keyParser) parse(b []byte) []key {
+	if len(b) == 0 {
+		return p.flush()
+	}
+
+	b = append(p.pending, b...)
+	p.pending = nil
+	var keys []key
+	for i := 0; i < len(b); i++ {
+		if key(b[i]) != keyEscape {
+			keys = append(keys, key(b[i]))
+			continue
+		}
+		if i+1 == len(b) {
+			p.pending = b[i:]
+			break
+		}
+		if b[i+1] != '[' && b[i+1] != 'O' {
+			keys = append(keys, keyEscape)
+			continue
+		}
+
+		// Parameters come before the final letter, as in ESC [ 1 ; 5 A
+		j := i + 2
+		for j < len(b) && (b[j] < 0x40 || b[j] > 0x7e) {
+			j++
+		}
+		if j == len(b) {
+			p.pending = b[i:]
+			break
+		}
+		if k, ok := arrowSequences[b[j]]; ok {
+			keys = append(keys, k)
+		}
+		i = j
+	}
+	return keys
+}
+
+// Nothing more is coming for now, so whatever was held back is keys of its
+// own: a lone ESC is the Esc key
+func (p *keyParser) flush() []key {
+	var keys []key
+	for _, c := range p.pending {
+		keys = append(keys, key(c))
+	}
+	p.pending = nil
+	return keys
+}
+
+// Plays the game in the terminal, reading the keyboard from stdin. The
+// terminal is put in raw mode for it, so keys come through as they're pressed
+// without being echoed, and put back as it was when the game is done.
+func runTerminal(tp *TerminalPlayer) error {
+	restore, err := rawTerminal()
+	if err != nil {
+		return err
+	}
+	defer restore()
+	return tp.Run(os.Stdin, os.Stdout)
+}
+
+// Puts the terminal on stdin in raw mode with stty, returning a func that puts
+// it back as it was. A read waits a tenth of a second at most, coming back
+// empty when nothing was typed.
+func rawTerminal() (restore func(), err error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("stdin isn't a terminal: %w", err)
+	}
+	if _, err := stty("-icanon", "-echo", "-isig", "min", "0", "time", "1"); err != nil {
+		return nil, err
+	}
+	return func() { stty(strings.TrimSpace(saved)) }, nil
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// Plays the game in the terminal, see playWindow
+func playTerminal(game *snake.Game, rival *agent.Agent) error {
+	return runTerminal(NewTerminalPlayer(game, nil, rival))
+}
+
+// Has the agent play the game in the terminal, see watchWindow
+func watchTerminal(game *snake.Game, ai *agent.Agent) error {
+	return runTerminal(NewTerminalPlayer(game, ai, nil))
+}
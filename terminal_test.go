@@ -0,0 +1,86 @@
+package main
+
+import (
+	"io"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseKeys(t *testing.T) {
+	testCases := []struct {
+		name  string
+		reads []string // what each read brings in, "" for one that comes back empty
+		want  []key
+	}{
+		{"Lone Esc", []string{"\x1b", ""}, []key{keyEscape}},
+		{"Esc then a key", []string{"\x1bq"}, []key{keyEscape, 'q'}},
+		{"Arrows", []string{"\x1b[A\x1b[B\x1b[C\x1b[D"}, []key{keyUp, keyDown, keyRight, keyLeft}},
+		{"Application arrows", []string{"\x1bOA\x1bOD"}, []key{keyUp, keyLeft}},
+		{"Arrow with modifiers", []string{"\x1b[1;5C"}, []key{keyRight}},
+		{"Other sequences", []string{"\x1b[H\x1b[3~w"}, []key{'w'}},
+		{"Split after Esc", []string{"\x1b", "[A"}, []key{keyUp}},
+		{"Split in the parameters", []string{"\x1b[1;", "5B", ""}, []key{keyDown}},
+		{"Unfinished sequence", []string{"\x1b[", ""}, []key{keyEscape, '['}},
+		{"WASD and q", []string{"wasd", "q"}, []key{'w', 'a', 's', 'd', 'q'}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var parser keyParser
+			var got []key
+			for _, read := range tc.reads {
+				got = append(got, parser.parse([]byte(read))...)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("parse(%q) = %v; want %v", tc.reads, got, tc.want)
+			}
+		})
+	}
+}
+
+// A terminal someone keeps typing w into
+type typing struct{}
+
+func (typing) Read(b []byte) (int, error) {
+	b[0] = 'w'
+	return 1, nil
+}
+
+// A terminal in raw mode nobody types into, where reads come back empty
+type idle struct{}
+
+func (idle) Read(b []byte) (int, error) {
+	time.Sleep(time.Millisecond)
+	return 0, nil
+}
+
+func TestReadKeysStopsWhenDone(t *testing.T) {
+	testCases := []struct {
+		name string
+		in   io.Reader
+	}{
+		{"Typing", typing{}},
+		{"Idle", idle{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			done := make(chan struct{})
+			keys := readKeys(tc.in, done)
+			close(done)
+
+			timeout := time.After(time.Second)
+			for {
+				select {
+				case _, ok := <-keys:
+					if !ok {
+						return
+					}
+				case <-timeout:
+					t.Fatalf("readKeys() still reading a second after done was closed")
+				}
+			}
+		})
+	}
+}
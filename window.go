@@ -0,0 +1,35 @@
+//go:build !headless
+
+package main
+
+import (
+	"github.com/casen/snakegame/agent"
+	"github.com/casen/snakegame/snake"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Opens a window where a human plays the game with the arrow keys. In a game
+// of two, the second snake is played by rival, or by a second human with WASD
+// when rival is nil.
+func playWindow(game *snake.Game, rival *agent.Agent) error {
+	if game.Players() > 1 {
+		return runWindow(NewVersusPlayer(game, rival))
+	}
+	return runWindow(NewGamePlayer(game, nil, false))
+}
+
+// Opens a window where the agent plays the game
+func watchWindow(game *snake.Game, ai *agent.Agent) error {
+	return runWindow(NewGamePlayer(game, ai, true))
+}
+
+// Opens a window showing a recorded episode
+func replayWindow(replayer *snake.Replayer) error {
+	return runWindow(NewReplayViewer(replayer))
+}
+
+func runWindow(game ebiten.Game) error {
+	ebiten.SetWindowSize(game.Layout(snake.ScreenWidth, snake.ScreenHeight))
+	ebiten.SetWindowTitle("Snake")
+	return ebiten.RunGame(game)
+}
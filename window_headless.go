@@ -0,0 +1,25 @@
+//go:build headless
+
+package main
+
+import (
+	"errors"
+
+	"github.com/casen/snakegame/agent"
+	"github.com/casen/snakegame/snake"
+)
+
+// Headless builds leave out ebiten, which can't even initialize without a display
+var errNoDisplay = errors.New("this build has no display support, rebuild without -tags headless")
+
+func playWindow(game *snake.Game, rival *agent.Agent) error {
+	return errNoDisplay
+}
+
+func watchWindow(game *snake.Game, ai *agent.Agent) error {
+	return errNoDisplay
+}
+
+func replayWindow(replayer *snake.Replayer) error {
+	return errNoDisplay
+}